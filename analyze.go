@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golang/tracefile"
+	"golang/tracestat"
+	"log"
+	"os"
+)
+
+// runAnalyze implements the analyze subcommand: a characterization of the
+// trace itself, written to stdout as text or JSON.
+func runAnalyze(args []string) {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	traceFormat := flags.String("format", tracefile.FormatAddr, "trace format: addr, spc, umass or msr")
+	pageSize := flags.Int("page-size", tracefile.PageSize, "page size in bytes used to split sized block requests into page accesses")
+	reportFormat := flags.String("report", "text", "report format: text or json")
+	flags.Usage = func() {
+		fmt.Println("program analyze [flags] <file>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *pageSize < 1 || (*reportFormat != "text" && *reportFormat != "json") {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	filePath := flags.Arg(0)
+	fs, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		fmt.Printf("%v does not exists\n", filePath)
+		os.Exit(1)
+	}
+
+	collector, err := collectFile(filePath, *traceFormat, *pageSize, fs.Name())
+	if err != nil {
+		log.Fatalf("error reading file: %v", err)
+	}
+	report := collector.Report()
+
+	if *reportFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// collectFile streams the trace into a Collector, so traces too large for
+// memory can be characterized.
+func collectFile(filePath string, format string, pageSize int, traceName string) (*tracestat.Collector, error) {
+	collector := tracestat.NewCollector(traceName)
+	if err := streamPages(filePath, format, pageSize, nil, collector.Add); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
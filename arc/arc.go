@@ -0,0 +1,217 @@
+package arc
+
+import (
+	"golang/simulator"
+
+	"github.com/secnot/orderedmap"
+)
+
+// ARC is the Adaptive Replacement Cache of Megiddo and Modha. T1 and T2 hold
+// resident pages seen once and at least twice; B1 and B2 remember the pages
+// recently evicted from them. A hit in B1 grows the target size p of T1, a
+// hit in B2 shrinks it.
+type ARC struct {
+	cacheSize      int
+	p              int
+	sampleInterval int
+	accesses       int
+	history        []simulator.Sample
+	hit            int
+	miss           int
+	readHit        int
+	readMiss       int
+	writeHit       int
+	writeBack      *simulator.WriteBack
+
+	// T1 and T2 hold resident pages, B1 and B2 are ghosts; all least recent
+	// first
+	t1 *orderedmap.OrderedMap
+	t2 *orderedmap.OrderedMap
+	b1 *orderedmap.OrderedMap
+	b2 *orderedmap.OrderedMap
+
+	simulator.Notifier
+}
+
+// NewARC builds an ARC of cacheSize pages that records p every
+// sampleInterval accesses.
+func NewARC(cacheSize, sampleInterval int) *ARC {
+	a := &ARC{
+		cacheSize:      cacheSize,
+		sampleInterval: sampleInterval,
+		t1:             orderedmap.NewOrderedMap(),
+		t2:             orderedmap.NewOrderedMap(),
+		b1:             orderedmap.NewOrderedMap(),
+		b2:             orderedmap.NewOrderedMap(),
+	}
+	a.writeBack = simulator.NewWriteBack(&a.Notifier)
+	return a
+}
+
+func (a *ARC) Get(trace simulator.Trace) (err error) {
+	block := trace.Addr
+
+	switch trace.Op {
+	case simulator.OpTrim:
+		a.t1.Delete(block)
+		a.t2.Delete(block)
+		a.b1.Delete(block)
+		a.b2.Delete(block)
+		a.writeBack.Discard(block)
+		return nil
+	case simulator.OpFlush:
+		a.writeBack.Flush()
+		return nil
+	}
+
+	a.access(block, trace.Op)
+
+	a.accesses++
+	if a.sampleInterval > 0 && a.accesses%a.sampleInterval == 0 {
+		a.history = append(a.history, simulator.Sample{Access: a.accesses, Value: a.p})
+	}
+	return nil
+}
+
+func (a *ARC) access(block int, op simulator.Op) {
+	write := op == simulator.OpWrite
+
+	// case I: hit in T1 or T2
+	if _, ok := a.t1.Get(block); ok {
+		a.countHit(block, write)
+		a.t1.Delete(block)
+		a.t2.Set(block, struct{}{})
+		a.writeBack.Access(block, op)
+		return
+	}
+	if _, ok := a.t2.Get(block); ok {
+		a.countHit(block, write)
+		a.t2.MoveLast(block)
+		a.writeBack.Access(block, op)
+		return
+	}
+
+	a.miss++
+	if !write {
+		a.readMiss++
+	}
+	a.Notify(simulator.EventMiss, block)
+	if a.cacheSize <= 0 {
+		if write {
+			a.writeBack.WriteThrough(block)
+		}
+		return
+	}
+
+	// case II: ghost hit in B1, favour recency
+	if _, ok := a.b1.Get(block); ok {
+		a.p = min(a.cacheSize, a.p+max(a.b2.Len()/a.b1.Len(), 1))
+		if a.full() {
+			a.replace(false)
+		}
+		a.b1.Delete(block)
+		a.t2.Set(block, struct{}{})
+		a.writeBack.Access(block, op)
+		return
+	}
+
+	// case III: ghost hit in B2, favour frequency
+	if _, ok := a.b2.Get(block); ok {
+		a.p = max(0, a.p-max(a.b1.Len()/a.b2.Len(), 1))
+		if a.full() {
+			a.replace(true)
+		}
+		a.b2.Delete(block)
+		a.t2.Set(block, struct{}{})
+		a.writeBack.Access(block, op)
+		return
+	}
+
+	// case IV: cold miss
+	if l1 := a.t1.Len() + a.b1.Len(); l1 == a.cacheSize {
+		if a.t1.Len() < a.cacheSize {
+			a.b1.PopFirst()
+			if a.full() {
+				a.replace(false)
+			}
+		} else {
+			key, _, _ := a.t1.PopFirst()
+			a.writeBack.Evict(key.(int))
+		}
+	} else if total := l1 + a.t2.Len() + a.b2.Len(); total >= a.cacheSize {
+		if total >= 2*a.cacheSize {
+			a.b2.PopFirst()
+		}
+		if a.full() {
+			a.replace(false)
+		}
+	}
+	a.t1.Set(block, struct{}{})
+	a.writeBack.Access(block, op)
+}
+
+// full reports whether T1 and T2 leave no room for another page. A TRIM can
+// free room while the ghost lists keep the directory at its full size, so the
+// directory size alone does not say whether to replace.
+func (a *ARC) full() bool {
+	return a.t1.Len()+a.t2.Len() >= a.cacheSize
+}
+
+// replace evicts the LRU page of T1 or T2 into its ghost list, depending on
+// how the size of T1 compares with the target p.
+func (a *ARC) replace(inB2 bool) {
+	if t1 := a.t1.Len(); t1 > 0 && (t1 > a.p || (inB2 && t1 == a.p)) {
+		key, _, _ := a.t1.PopFirst()
+		a.b1.Set(key, struct{}{})
+		a.writeBack.Evict(key.(int))
+		return
+	}
+	if key, _, ok := a.t2.PopFirst(); ok {
+		a.b2.Set(key, struct{}{})
+		a.writeBack.Evict(key.(int))
+	}
+}
+
+func (a *ARC) countHit(block int, write bool) {
+	a.hit++
+	a.Notify(simulator.EventHit, block)
+	if write {
+		a.writeHit++
+	} else {
+		a.readHit++
+	}
+}
+
+// TargetHistory returns the target size of T1 sampled during the run.
+func (a *ARC) TargetHistory() []simulator.Sample {
+	return a.history
+}
+
+func (a *ARC) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      a.cacheSize,
+		Hits:           a.hit,
+		Misses:         a.miss,
+		ReadHits:       a.readHit,
+		ReadMisses:     a.readMiss,
+		WriteHits:      a.writeHit,
+		DirtyEvictions: a.writeBack.DirtyEvictions,
+		CleanEvictions: a.writeBack.CleanEvictions,
+		FlashWrites:    a.writeBack.FlashWrites,
+		ResidentSize:   a.t1.Len() + a.t2.Len(),
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package arc
+
+import (
+	"math/rand"
+	"testing"
+
+	"golang/simulator"
+)
+
+func evictions(a *ARC) int {
+	stats := a.Stats()
+	return stats.CleanEvictions + stats.DirtyEvictions
+}
+
+func get(t *testing.T, a *ARC, addr int, op simulator.Op) {
+	t.Helper()
+	if err := a.Get(simulator.Trace{Addr: addr, Op: op}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A trimmed page leaves room the next miss must use instead of evicting,
+// whether it hits a ghost list or not.
+func TestTrimLeavesRoom(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace []simulator.Trace
+	}{
+		{"cold miss", []simulator.Trace{{Addr: 1, Op: simulator.OpTrim}, {Addr: 6}}},
+		{"B1 hit", []simulator.Trace{{Addr: 1}, {Addr: 5}, {Addr: 3, Op: simulator.OpTrim}, {Addr: 2}}},
+		{"B2 hit", []simulator.Trace{{Addr: 1}, {Addr: 5}, {Addr: 2}, {Addr: 3}, {Addr: 4, Op: simulator.OpTrim}, {Addr: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewARC(4, 0)
+			for addr := 1; addr <= 4; addr++ {
+				get(t, a, addr, simulator.OpRead)
+			}
+			last := len(tt.trace) - 1
+			for _, trace := range tt.trace[:last] {
+				get(t, a, trace.Addr, trace.Op)
+			}
+			before := evictions(a)
+			get(t, a, tt.trace[last].Addr, tt.trace[last].Op)
+			if got := evictions(a) - before; got != 0 {
+				t.Errorf("evicted %d pages with room left", got)
+			}
+			if got := a.Stats().ResidentSize; got != 4 {
+				t.Errorf("resident size %d, want 4", got)
+			}
+		})
+	}
+}
+
+// Mixed with trims, ARC evicts only when full and never outgrows its size.
+func TestEvictOnlyWhenFull(t *testing.T) {
+	const cacheSize = 16
+	a := NewARC(cacheSize, 0)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		op := simulator.Op(r.Intn(3))
+		addr := r.Intn(4 * cacheSize)
+		resident, before := a.Stats().ResidentSize, evictions(a)
+		get(t, a, addr, op)
+
+		if evicted := evictions(a) - before; evicted > 0 && resident < cacheSize {
+			t.Fatalf("access %d: evicted %d pages with %d of %d resident", i, evicted, resident, cacheSize)
+		}
+		stats := a.Stats()
+		if stats.ResidentSize > cacheSize {
+			t.Fatalf("access %d: %d pages resident in a cache of %d", i, stats.ResidentSize, cacheSize)
+		}
+		if a.b1.Len()+a.b2.Len()+stats.ResidentSize > 2*cacheSize {
+			t.Fatalf("access %d: directory holds %d pages", i, a.b1.Len()+a.b2.Len()+stats.ResidentSize)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cflru
+
+import (
+	"log"
+
+	"golang/simulator"
+
+	"github.com/secnot/orderedmap"
+)
+
+// CFLRU is Clean-First LRU. The LRU end of the list is a clean-first window:
+// on a miss the least recently used clean page inside the window is evicted,
+// and a dirty page is only evicted when the window holds no clean page.
+type CFLRU struct {
+	cacheSize  int
+	windowSize int
+	hit        int
+	miss       int
+	readHit    int
+	readMiss   int
+	writeHit   int
+	writeBack  *simulator.WriteBack
+
+	// list holds the resident pages, least recently used first
+	list *orderedmap.OrderedMap
+
+	simulator.Notifier
+}
+
+// NewCFLRU builds a cache of cacheSize pages whose clean-first window covers
+// window percent of the cache.
+func NewCFLRU(cacheSize, window int) *CFLRU {
+	if window > 100 || window < 0 {
+		log.Fatal("window must be between 0 and 100")
+	}
+	c := &CFLRU{
+		cacheSize:  cacheSize,
+		windowSize: window * cacheSize / 100,
+		list:       orderedmap.NewOrderedMap(),
+	}
+	c.writeBack = simulator.NewWriteBack(&c.Notifier)
+	return c
+}
+
+func (c *CFLRU) Get(trace simulator.Trace) (err error) {
+	block := trace.Addr
+	write := trace.Op == simulator.OpWrite
+
+	switch trace.Op {
+	case simulator.OpTrim:
+		c.list.Delete(block)
+		c.writeBack.Discard(block)
+		return nil
+	case simulator.OpFlush:
+		c.writeBack.Flush()
+		return nil
+	}
+
+	if _, ok := c.list.Get(block); ok {
+		c.hit++
+		c.Notify(simulator.EventHit, block)
+		if write {
+			c.writeHit++
+		} else {
+			c.readHit++
+		}
+		c.writeBack.Access(block, trace.Op)
+		c.list.MoveLast(block)
+		return nil
+	}
+
+	c.miss++
+	if !write {
+		c.readMiss++
+	}
+	c.Notify(simulator.EventMiss, block)
+	if c.cacheSize <= 0 {
+		if write {
+			c.writeBack.WriteThrough(block)
+		}
+		return nil
+	}
+	if c.list.Len() >= c.cacheSize {
+		c.evict()
+	}
+	c.list.Set(block, struct{}{})
+	c.writeBack.Access(block, trace.Op)
+	return nil
+}
+
+func (c *CFLRU) evict() {
+	iter := c.list.Iter()
+	for i := 0; i < c.windowSize; i++ {
+		key, _, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if !c.writeBack.IsDirty(key.(int)) {
+			c.list.Delete(key)
+			c.writeBack.Evict(key.(int))
+			return
+		}
+	}
+
+	// no clean page in the window, fall back to the LRU page
+	key, _, _ := c.list.PopFirst()
+	c.writeBack.Evict(key.(int))
+}
+
+func (c *CFLRU) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      c.cacheSize,
+		Hits:           c.hit,
+		Misses:         c.miss,
+		ReadHits:       c.readHit,
+		ReadMisses:     c.readMiss,
+		WriteHits:      c.writeHit,
+		DirtyEvictions: c.writeBack.DirtyEvictions,
+		CleanEvictions: c.writeBack.CleanEvictions,
+		FlashWrites:    c.writeBack.FlashWrites,
+		ResidentSize:   c.list.Len(),
+		ListSize:       c.list.Len(),
+	}
+}
@@ -0,0 +1,132 @@
+package clock
+
+import (
+	"golang/simulator"
+)
+
+type (
+	frame struct {
+		addr       int
+		referenced bool
+	}
+
+	// CLOCK approximates LRU with a reference bit per frame and a hand that
+	// sweeps the frames, clearing set bits until it finds a frame to evict.
+	CLOCK struct {
+		cacheSize int
+		hit       int
+		miss      int
+		readHit   int
+		readMiss  int
+		writeHit  int
+		writeBack *simulator.WriteBack
+
+		frames []frame
+		index  map[int]int
+		free   []int
+		hand   int
+
+		simulator.Notifier
+	}
+)
+
+func NewCLOCK(cacheSize int) *CLOCK {
+	free := make([]int, cacheSize)
+	for i := range free {
+		free[i] = cacheSize - 1 - i
+	}
+	c := &CLOCK{
+		cacheSize: cacheSize,
+		frames:    make([]frame, cacheSize),
+		index:     make(map[int]int, cacheSize),
+		free:      free,
+	}
+	c.writeBack = simulator.NewWriteBack(&c.Notifier)
+	return c
+}
+
+func (c *CLOCK) Get(trace simulator.Trace) (err error) {
+	block := trace.Addr
+	write := trace.Op == simulator.OpWrite
+
+	switch trace.Op {
+	case simulator.OpTrim:
+		if i, ok := c.index[block]; ok {
+			c.frames[i] = frame{}
+			delete(c.index, block)
+			c.free = append(c.free, i)
+		}
+		c.writeBack.Discard(block)
+		return nil
+	case simulator.OpFlush:
+		c.writeBack.Flush()
+		return nil
+	}
+
+	if i, ok := c.index[block]; ok {
+		c.hit++
+		c.Notify(simulator.EventHit, block)
+		if write {
+			c.writeHit++
+		} else {
+			c.readHit++
+		}
+		c.frames[i].referenced = true
+		c.writeBack.Access(block, trace.Op)
+		return nil
+	}
+
+	c.miss++
+	if !write {
+		c.readMiss++
+	}
+	c.Notify(simulator.EventMiss, block)
+	if c.cacheSize <= 0 {
+		if write {
+			c.writeBack.WriteThrough(block)
+		}
+		return nil
+	}
+
+	var i int
+	if n := len(c.free); n > 0 {
+		i = c.free[n-1]
+		c.free = c.free[:n-1]
+	} else {
+		i = c.evict()
+	}
+	c.frames[i] = frame{addr: block}
+	c.index[block] = i
+	c.writeBack.Access(block, trace.Op)
+	return nil
+}
+
+// evict advances the hand to the first frame without its reference bit set,
+// empties it and returns its index.
+func (c *CLOCK) evict() int {
+	for c.frames[c.hand].referenced {
+		c.frames[c.hand].referenced = false
+		c.hand = (c.hand + 1) % c.cacheSize
+	}
+
+	i := c.hand
+	c.writeBack.Evict(c.frames[i].addr)
+	delete(c.index, c.frames[i].addr)
+	c.hand = (c.hand + 1) % c.cacheSize
+	return i
+}
+
+func (c *CLOCK) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      c.cacheSize,
+		Hits:           c.hit,
+		Misses:         c.miss,
+		ReadHits:       c.readHit,
+		ReadMisses:     c.readMiss,
+		WriteHits:      c.writeHit,
+		DirtyEvictions: c.writeBack.DirtyEvictions,
+		CleanEvictions: c.writeBack.CleanEvictions,
+		FlashWrites:    c.writeBack.FlashWrites,
+		ResidentSize:   len(c.index),
+	}
+}
@@ -0,0 +1,59 @@
+package clock
+
+import (
+	"testing"
+
+	"golang/simulator"
+)
+
+func replay(t *testing.T, c *CLOCK, traces []simulator.Trace) {
+	t.Helper()
+	for i, trace := range traces {
+		if err := c.Get(trace); err != nil {
+			t.Fatalf("access %d: %v", i, err)
+		}
+	}
+}
+
+func TestEvictions(t *testing.T) {
+	r := func(addr int) simulator.Trace { return simulator.Trace{Addr: addr} }
+	w := func(addr int) simulator.Trace { return simulator.Trace{Addr: addr, Op: simulator.OpWrite} }
+	trim := func(addr int) simulator.Trace { return simulator.Trace{Addr: addr, Op: simulator.OpTrim} }
+	flush := simulator.Trace{Op: simulator.OpFlush}
+
+	tests := []struct {
+		name     string
+		traces   []simulator.Trace
+		resident []int
+		dirty    int
+		clean    int
+		writes   int
+	}{
+		// 1 is referenced, so the hand clears its bit and takes 2
+		{"second chance", []simulator.Trace{r(1), r(2), r(1), r(3)}, []int{1, 3}, 0, 1, 0},
+		{"every bit set", []simulator.Trace{r(1), r(2), r(1), r(2), r(3)}, []int{2, 3}, 0, 1, 0},
+		{"dirty eviction", []simulator.Trace{w(1), r(2), r(3)}, []int{2, 3}, 1, 0, 1},
+		{"write hit dirties", []simulator.Trace{r(1), w(1), r(2), r(3)}, []int{1, 3}, 0, 1, 0},
+		{"trim frees a frame", []simulator.Trace{w(1), r(2), trim(1), r(3)}, []int{2, 3}, 0, 0, 0},
+		{"flush cleans", []simulator.Trace{w(1), w(2), flush, r(3)}, []int{2, 3}, 0, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCLOCK(2)
+			replay(t, c, tt.traces)
+			for _, addr := range tt.resident {
+				if _, ok := c.index[addr]; !ok {
+					t.Errorf("page %d not resident", addr)
+				}
+			}
+			stats := c.Stats()
+			if stats.ResidentSize != len(tt.resident) {
+				t.Errorf("%d pages resident, want %d", stats.ResidentSize, len(tt.resident))
+			}
+			if stats.DirtyEvictions != tt.dirty || stats.CleanEvictions != tt.clean || stats.FlashWrites != tt.writes {
+				t.Errorf("%d dirty and %d clean evictions, %d flash writes; want %d, %d and %d",
+					stats.DirtyEvictions, stats.CleanEvictions, stats.FlashWrites, tt.dirty, tt.clean, tt.writes)
+			}
+		})
+	}
+}
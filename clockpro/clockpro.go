@@ -0,0 +1,295 @@
+package clockpro
+
+import (
+	"golang/simulator"
+)
+
+type pageType int
+
+const (
+	cold pageType = iota
+	hot
+	// test pages are non-resident cold pages kept only for their history
+	test
+)
+
+type (
+	page struct {
+		addr       int
+		kind       pageType
+		referenced bool
+		// inTest is set while a resident cold page is in its test period
+		inTest     bool
+		prev, next *page
+	}
+
+	// CLOCKPro is the CLOCK-Pro approximation of LIRS (Jiang, Chen and Zhang).
+	// Hot pages play the role of LIR blocks, resident cold pages of resident
+	// HIR blocks and test pages of non-resident HIR blocks in the LIRS stack.
+	// All of them sit on a single clock swept by three hands: the cold hand
+	// evicts cold pages, the hot hand demotes hot pages and the test hand ends
+	// test periods, dropping the test pages it passes. The cold target adapts:
+	// a test page that is referenced again grows it, an expiring test page
+	// shrinks it.
+	// Hot/cold transitions are reported as LIR promotions and HIR demotions.
+	//
+	// A resident cold page starts a test period when it is loaded, and the hot
+	// and test hands end it as they pass the page. When the cold hand reaches
+	// a referenced cold page it promotes the page if it is still in its test
+	// period and otherwise starts a new one, leaving the page cold. An
+	// unreferenced cold page is evicted, and only kept as a test page if it is
+	// in its test period.
+	CLOCKPro struct {
+		cacheSize  int
+		coldTarget int
+		hit        int
+		miss       int
+		readHit    int
+		readMiss   int
+		writeHit   int
+		writeBack  *simulator.WriteBack
+
+		pages     map[int]*page
+		countHot  int
+		countCold int
+		countTest int
+		handHot   *page
+		handCold  *page
+		handTest  *page
+
+		simulator.Notifier
+	}
+)
+
+func NewCLOCKPro(cacheSize int) *CLOCKPro {
+	c := &CLOCKPro{
+		cacheSize:  cacheSize,
+		coldTarget: cacheSize,
+		pages:      make(map[int]*page, 2*cacheSize),
+	}
+	c.writeBack = simulator.NewWriteBack(&c.Notifier)
+	return c
+}
+
+func (c *CLOCKPro) Get(trace simulator.Trace) (err error) {
+	block := trace.Addr
+	write := trace.Op == simulator.OpWrite
+
+	switch trace.Op {
+	case simulator.OpTrim:
+		if p, ok := c.pages[block]; ok {
+			c.count(p.kind, -1)
+			c.unlink(p)
+		}
+		c.writeBack.Discard(block)
+		return nil
+	case simulator.OpFlush:
+		c.writeBack.Flush()
+		return nil
+	}
+
+	p, ok := c.pages[block]
+	if ok && p.kind != test {
+		c.hit++
+		c.Notify(simulator.EventHit, block)
+		if write {
+			c.writeHit++
+		} else {
+			c.readHit++
+		}
+		p.referenced = true
+		c.writeBack.Access(block, trace.Op)
+		return nil
+	}
+
+	c.miss++
+	if !write {
+		c.readMiss++
+	}
+	c.Notify(simulator.EventMiss, block)
+	if c.cacheSize <= 0 {
+		if write {
+			c.writeBack.WriteThrough(block)
+		}
+		return nil
+	}
+	// every page accessed from here on ends up resident
+	defer c.writeBack.Access(block, trace.Op)
+
+	if !ok {
+		c.insert(&page{addr: block, kind: cold, inTest: true})
+		c.countCold++
+		return nil
+	}
+
+	// a test page was referenced again within its test period: its reuse
+	// distance is short enough to make it hot, and cold pages deserve more room
+	if c.coldTarget < c.cacheSize {
+		c.coldTarget++
+	}
+	c.countTest--
+	c.unlink(p)
+	c.Notify(simulator.EventPromotedToLIR, block)
+	p.kind = hot
+	p.referenced = false
+	c.insert(p)
+	c.countHot++
+	return nil
+}
+
+// insert makes room for p and links it just behind the hot hand, which is the
+// head of the clock.
+func (c *CLOCKPro) insert(p *page) {
+	for c.cacheSize <= c.countHot+c.countCold {
+		c.runHandCold()
+	}
+
+	c.pages[p.addr] = p
+	if c.handHot == nil {
+		p.prev, p.next = p, p
+		c.handHot, c.handCold, c.handTest = p, p, p
+		return
+	}
+	p.next = c.handHot
+	p.prev = c.handHot.prev
+	p.prev.next = p
+	c.handHot.prev = p
+	if c.handCold == c.handHot {
+		c.handCold = c.handCold.prev
+	}
+}
+
+// unlink removes p from the clock, stepping back any hand that points at it.
+func (c *CLOCKPro) unlink(p *page) {
+	delete(c.pages, p.addr)
+	if p.next == p {
+		c.handHot, c.handCold, c.handTest = nil, nil, nil
+		return
+	}
+	if c.handHot == p {
+		c.handHot = p.prev
+	}
+	if c.handCold == p {
+		c.handCold = p.prev
+	}
+	if c.handTest == p {
+		c.handTest = p.prev
+	}
+	p.prev.next = p.next
+	p.next.prev = p.prev
+}
+
+func (c *CLOCKPro) count(kind pageType, delta int) {
+	switch kind {
+	case hot:
+		c.countHot += delta
+	case cold:
+		c.countCold += delta
+	case test:
+		c.countTest += delta
+	}
+}
+
+func (c *CLOCKPro) runHandCold() {
+	p := c.handCold
+	if p.kind == cold {
+		switch {
+		case p.referenced && p.inTest:
+			// referenced during its test period: promote
+			c.Notify(simulator.EventPromotedToLIR, p.addr)
+			p.kind = hot
+			p.referenced = false
+			p.inTest = false
+			c.countCold--
+			c.countHot++
+		case p.referenced:
+			// referenced too late to be hot: stay cold for another test period
+			p.referenced = false
+			p.inTest = true
+		default:
+			c.writeBack.Evict(p.addr)
+			c.countCold--
+			if !p.inTest {
+				// nothing left to learn from the page
+				c.unlink(p)
+				if c.handCold == nil {
+					// it was the last page on the clock
+					return
+				}
+				break
+			}
+			// keep the page as a test page for the rest of its test period
+			p.kind = test
+			p.inTest = false
+			c.countTest++
+			for c.cacheSize < c.countTest {
+				c.runHandTest()
+			}
+		}
+	}
+	c.handCold = c.handCold.next
+	for c.cacheSize-c.coldTarget < c.countHot {
+		c.runHandHot()
+	}
+}
+
+func (c *CLOCKPro) runHandHot() {
+	if c.handHot == c.handTest {
+		c.runHandTest()
+	}
+	p := c.handHot
+	switch p.kind {
+	case hot:
+		if p.referenced {
+			p.referenced = false
+		} else {
+			c.Notify(simulator.EventDemotedToHIR, p.addr)
+			p.kind = cold
+			c.countHot--
+			c.countCold++
+		}
+	case cold:
+		p.inTest = false
+	}
+	c.handHot = c.handHot.next
+}
+
+func (c *CLOCKPro) runHandTest() {
+	if c.countTest == 0 {
+		// nothing to expire; also stops the hands chasing each other forever
+		// when the clock holds a single hot page
+		return
+	}
+	if c.handTest == c.handCold {
+		c.runHandCold()
+	}
+	p := c.handTest
+	switch p.kind {
+	case test:
+		// the test period ended without a reference: shrink the cold target
+		c.unlink(p)
+		c.countTest--
+		if c.coldTarget > 1 {
+			c.coldTarget--
+		}
+	case cold:
+		p.inTest = false
+	}
+	c.handTest = c.handTest.next
+}
+
+func (c *CLOCKPro) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      c.cacheSize,
+		Hits:           c.hit,
+		Misses:         c.miss,
+		ReadHits:       c.readHit,
+		ReadMisses:     c.readMiss,
+		WriteHits:      c.writeHit,
+		DirtyEvictions: c.writeBack.DirtyEvictions,
+		CleanEvictions: c.writeBack.CleanEvictions,
+		FlashWrites:    c.writeBack.FlashWrites,
+		ResidentSize:   c.countHot + c.countCold,
+		ListSize:       len(c.pages),
+	}
+}
@@ -0,0 +1,151 @@
+package clockpro
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"golang/simulator"
+)
+
+type recorder []simulator.Event
+
+func (r *recorder) Observe(e simulator.Event) {
+	*r = append(*r, e)
+}
+
+func get(t *testing.T, c *CLOCKPro, addr int, op simulator.Op) {
+	t.Helper()
+	if err := c.Get(simulator.Trace{Addr: addr, Op: op}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// What the cold hand does with a resident cold page depends on its reference
+// bit and on whether it is still in its test period.
+func TestColdHand(t *testing.T) {
+	tests := []struct {
+		name       string
+		referenced bool
+		inTest     bool
+		promoted   bool
+		want       pageType
+		dropped    bool
+	}{
+		{"referenced in its test period", true, true, true, hot, false},
+		{"referenced after its test period", true, false, false, cold, false},
+		{"unreferenced in its test period", false, true, false, test, false},
+		{"unreferenced after its test period", false, false, false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCLOCKPro(4)
+			// leave room for hot pages, or the hot hand demotes them at once
+			c.coldTarget = 2
+			for addr := 1; addr <= 4; addr++ {
+				get(t, c, addr, simulator.OpRead)
+			}
+			p := c.handCold
+			p.referenced, p.inTest = tt.referenced, tt.inTest
+			var events recorder
+			c.SetObserver(&events)
+			get(t, c, 5, simulator.OpRead)
+
+			promoted := false
+			for _, e := range events {
+				if e.Kind == simulator.EventPromotedToLIR && e.Addr == p.addr {
+					promoted = true
+				}
+			}
+			if promoted != tt.promoted {
+				t.Errorf("page promoted: %v, want %v", promoted, tt.promoted)
+			}
+			q, ok := c.pages[p.addr]
+			if tt.dropped {
+				if ok {
+					t.Errorf("page kept as kind %v, want it dropped", q.kind)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("page dropped")
+			}
+			if q.kind != tt.want {
+				t.Errorf("page of kind %v, want %v", q.kind, tt.want)
+			}
+			if q.kind == cold && (!q.inTest || q.referenced) {
+				t.Errorf("cold page in test %v, referenced %v; want a new test period", q.inTest, q.referenced)
+			}
+		})
+	}
+}
+
+// The hot hand ends the test period of the cold pages it passes.
+func TestHotHandEndsTestPeriod(t *testing.T) {
+	c := NewCLOCKPro(4)
+	for addr := 1; addr <= 4; addr++ {
+		get(t, c, addr, simulator.OpRead)
+	}
+	p := c.handHot
+	if p.kind != cold || !p.inTest {
+		t.Fatalf("new page of kind %v, in test %v; want a cold page in its test period", p.kind, p.inTest)
+	}
+	c.runHandHot()
+	if p.inTest {
+		t.Error("cold page still in its test period after the hot hand passed")
+	}
+}
+
+// A flush writes back the dirty resident pages in address order.
+func TestFlushOrder(t *testing.T) {
+	c := NewCLOCKPro(16)
+	for _, addr := range []int{9, 3, 14, 1, 7, 12} {
+		get(t, c, addr, simulator.OpWrite)
+	}
+	get(t, c, 5, simulator.OpRead)
+	var events recorder
+	c.SetObserver(&events)
+	get(t, c, 0, simulator.OpFlush)
+
+	var flushed []int
+	for _, e := range events {
+		if e.Kind == simulator.EventFlushed {
+			flushed = append(flushed, e.Addr)
+		}
+	}
+	if len(flushed) != 6 || !sort.IntsAreSorted(flushed) {
+		t.Errorf("flushed %v, want the 6 dirty pages in address order", flushed)
+	}
+	if got := c.Stats().FlashWrites; got != 6 {
+		t.Errorf("%d flash writes, want 6", got)
+	}
+}
+
+// The page counts agree with the clock, and neither the resident pages nor
+// the test pages outgrow the cache.
+func TestCounts(t *testing.T) {
+	const cacheSize = 16
+	c := NewCLOCKPro(cacheSize)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		addr := r.Intn(4 * cacheSize)
+		if r.Intn(4) == 0 {
+			// a hot set the cold pages stream past
+			addr %= cacheSize / 2
+		}
+		get(t, c, addr, simulator.Op(r.Intn(3)))
+
+		counts := map[pageType]int{}
+		for _, p := range c.pages {
+			counts[p.kind]++
+		}
+		if counts[hot] != c.countHot || counts[cold] != c.countCold || counts[test] != c.countTest {
+			t.Fatalf("access %d: counted %d hot, %d cold and %d test pages, the clock holds %v",
+				i, c.countHot, c.countCold, c.countTest, counts)
+		}
+		if c.countHot+c.countCold > cacheSize || c.countTest > cacheSize {
+			t.Fatalf("access %d: %d resident and %d test pages in a cache of %d",
+				i, c.countHot+c.countCold, c.countTest, cacheSize)
+		}
+	}
+}
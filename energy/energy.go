@@ -0,0 +1,65 @@
+package energy
+
+import (
+	"fmt"
+
+	"golang/ftl"
+	"golang/simulator"
+)
+
+// Config holds the energy of one operation, in microjoules.
+type Config struct {
+	DRAM    float64
+	Read    float64
+	Program float64
+	Erase   float64
+	// PagesPerBlock amortizes erases when no FTL counted them: every block
+	// is erased once per PagesPerBlock pages programmed.
+	PagesPerBlock int
+}
+
+func (c Config) Validate() error {
+	if c.DRAM < 0 || c.Read < 0 || c.Program < 0 || c.Erase < 0 || c.PagesPerBlock < 1 {
+		return fmt.Errorf("invalid energy model: dram %v, read %v, program %v, erase %v uJ, %d pages per block",
+			c.DRAM, c.Read, c.Program, c.Erase, c.PagesPerBlock)
+	}
+	return nil
+}
+
+// Stats splits the energy of a run, in joules, by the kind of operation.
+type Stats struct {
+	DRAM    float64 `json:"dram_joules"`
+	Read    float64 `json:"read_joules"`
+	Program float64 `json:"program_joules"`
+	Erase   float64 `json:"erase_joules"`
+	Total   float64 `json:"total_joules"`
+}
+
+// Estimate converts the counts of a run into energy. Every access touches
+// DRAM, every read miss reads its page from flash, as the latency model
+// charges it, and every write-back programs a page. When flash is set its
+// erases and garbage collection copies are used, otherwise erases are
+// amortized over the pages programmed.
+func (c Config) Estimate(stats simulator.Stats, flash *ftl.Stats) Stats {
+	reads := stats.ReadMisses
+	programs := stats.FlashWrites
+	erases := float64(programs) / float64(c.PagesPerBlock)
+	if flash != nil {
+		reads += flash.GCCopies
+		programs = flash.FlashWrites
+		erases = float64(flash.Erases)
+	}
+
+	e := Stats{
+		DRAM:    joules(float64(stats.Accesses()) * c.DRAM),
+		Read:    joules(float64(reads) * c.Read),
+		Program: joules(float64(programs) * c.Program),
+		Erase:   joules(erases * c.Erase),
+	}
+	e.Total = e.DRAM + e.Read + e.Program + e.Erase
+	return e
+}
+
+func joules(microjoules float64) float64 {
+	return microjoules / 1e6
+}
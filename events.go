@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"golang/simulator"
+	"io"
+	"sync"
+)
+
+var (
+	eventsPath = flag.String("events", "", "write the internal events of every simulator as JSON lines to this file")
+	eventsFrom = flag.Int("events-from", 0, "first trace index logged by -events")
+	eventsTo   = flag.Int("events-to", -1, "last trace index logged by -events (-1 for the end of the trace)")
+	eventsAddr = flag.Int("events-addr", -1, "only log the events of this block address (-1 for every block)")
+)
+
+// events is the log shared by every tracer, nil unless -events is set.
+var events *eventLog
+
+type eventRecord struct {
+	Algorithm string `json:"algorithm"`
+	CacheSize int    `json:"cache_size"`
+	Index     int    `json:"index"`
+	Op        string `json:"op"`
+	Addr      int    `json:"addr"`
+	Event     string `json:"event"`
+}
+
+// eventLog serializes the records of concurrent simulators into one stream.
+type eventLog struct {
+	mu  sync.Mutex
+	buf *bufio.Writer
+	enc *json.Encoder
+	err error
+}
+
+func newEventLog(w io.Writer) *eventLog {
+	buf := bufio.NewWriter(w)
+	return &eventLog{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (l *eventLog) write(record eventRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = l.enc.Encode(record)
+	}
+}
+
+// Flush writes out buffered records and returns the first error met.
+func (l *eventLog) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return l.err
+	}
+	return l.buf.Flush()
+}
+
+// tracer observes one simulator and tags its events with the trace index and
+// operation being simulated. A nil tracer does nothing.
+type tracer struct {
+	job   job
+	index int
+	op    simulator.Op
+}
+
+// at must be called before the access at trace index is simulated.
+func (t *tracer) at(index int, op simulator.Op) {
+	if t != nil {
+		t.index = index
+		t.op = op
+	}
+}
+
+func (t *tracer) Observe(e simulator.Event) {
+	if t.index < *eventsFrom || (*eventsTo >= 0 && t.index > *eventsTo) {
+		return
+	}
+	if *eventsAddr >= 0 && e.Addr != *eventsAddr {
+		return
+	}
+	events.write(eventRecord{
+		Algorithm: t.job.algorithm,
+		CacheSize: t.job.cacheSize,
+		Index:     t.index,
+		Op:        t.op.String(),
+		Addr:      e.Addr,
+		Event:     e.Kind.String(),
+	})
+}
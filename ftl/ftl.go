@@ -0,0 +1,244 @@
+package ftl
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang/simulator"
+)
+
+// Garbage collection policies, choosing which block to reclaim.
+const (
+	// Greedy reclaims the block with the fewest valid pages.
+	Greedy = "greedy"
+	// CostBenefit weighs the space reclaimed against the cost of copying the
+	// valid pages and favours blocks that have not changed for a long time.
+	CostBenefit = "cost-benefit"
+)
+
+var ErrFull = errors.New("flash is full: no block has an invalid page to reclaim")
+
+type Config struct {
+	// LogicalPages is the capacity exported to the host.
+	LogicalPages  int
+	PagesPerBlock int
+	// OverProvisioning is the extra physical space as a fraction of the
+	// logical capacity, e.g. 0.07 for 7%.
+	OverProvisioning float64
+	GC               string
+}
+
+type Stats struct {
+	HostWrites         int     `json:"host_writes"`
+	FlashWrites        int     `json:"flash_writes"`
+	GCCopies           int     `json:"gc_copies"`
+	Erases             int     `json:"erases"`
+	WriteAmplification float64 `json:"write_amplification"`
+	BlockErases        []int   `json:"block_erases"`
+}
+
+type block struct {
+	// lpns holds the logical page stored in each programmed page, -1 once
+	// the page has been invalidated
+	lpns    []int
+	valid   int
+	erases  int
+	written int
+}
+
+// FTL is a page-mapped flash translation layer. Host writes are appended to
+// the active block and the pages garbage collection moves to a block of their
+// own. One free block is always kept in reserve so garbage collection has
+// somewhere to copy to.
+//
+// FTL is a simulator.Observer: fed the events of a cache it programs every
+// page the cache writes back and invalidates every trimmed page.
+type FTL struct {
+	config   Config
+	blocks   []block
+	free     []int
+	active   int
+	gcActive int
+	mapping  map[int]int
+	clock    int
+	stats    Stats
+	err      error
+}
+
+func New(config Config) (*FTL, error) {
+	if config.LogicalPages < 1 || config.PagesPerBlock < 1 || config.OverProvisioning < 0 {
+		return nil, fmt.Errorf("invalid flash geometry: %d logical pages, %d pages per block, %v over-provisioning",
+			config.LogicalPages, config.PagesPerBlock, config.OverProvisioning)
+	}
+	config.GC = strings.ToLower(config.GC)
+	if config.GC != Greedy && config.GC != CostBenefit {
+		return nil, fmt.Errorf("unknown garbage collection policy %q", config.GC)
+	}
+
+	// the logical capacity and its over-provisioning, plus the block
+	// garbage collection writes to and the reserve
+	physical := int(float64(config.LogicalPages) * (1 + config.OverProvisioning))
+	count := (physical+config.PagesPerBlock-1)/config.PagesPerBlock + 2
+
+	f := &FTL{
+		config:   config,
+		blocks:   make([]block, count),
+		active:   0,
+		gcActive: 1,
+		mapping:  make(map[int]int),
+	}
+	for i := count - 1; i > 1; i-- {
+		f.free = append(f.free, i)
+	}
+	return f, nil
+}
+
+func (f *FTL) Observe(e simulator.Event) {
+	if f.err != nil {
+		return
+	}
+	switch e.Kind {
+	case simulator.EventEvictedDirty, simulator.EventFlushed, simulator.EventWrittenThrough:
+		f.err = f.Write(e.Addr)
+	case simulator.EventTrimmed:
+		f.Trim(e.Addr)
+	}
+}
+
+// Err returns the error that stopped the FTL while observing events.
+func (f *FTL) Err() error {
+	return f.err
+}
+
+// Write programs a new version of lpn and invalidates the previous one.
+func (f *FTL) Write(lpn int) error {
+	if _, ok := f.mapping[lpn]; !ok && len(f.mapping) == f.config.LogicalPages {
+		return fmt.Errorf("page %d does not fit in the %d logical pages of the flash", lpn, f.config.LogicalPages)
+	}
+	f.clock++
+	f.Trim(lpn)
+
+	if f.full(f.active) {
+		for len(f.free) < 2 {
+			if err := f.collect(); err != nil {
+				return err
+			}
+		}
+		f.active = f.take()
+	}
+	f.program(f.active, lpn)
+	f.stats.HostWrites++
+	return nil
+}
+
+// Trim invalidates lpn without writing anything.
+func (f *FTL) Trim(lpn int) {
+	ppn, ok := f.mapping[lpn]
+	if !ok {
+		return
+	}
+	b := &f.blocks[ppn/f.config.PagesPerBlock]
+	b.lpns[ppn%f.config.PagesPerBlock] = -1
+	b.valid--
+	delete(f.mapping, lpn)
+}
+
+func (f *FTL) full(i int) bool {
+	return len(f.blocks[i].lpns) == f.config.PagesPerBlock
+}
+
+func (f *FTL) take() int {
+	i := f.free[len(f.free)-1]
+	f.free = f.free[:len(f.free)-1]
+	return i
+}
+
+func (f *FTL) program(i, lpn int) {
+	b := &f.blocks[i]
+	f.mapping[lpn] = i*f.config.PagesPerBlock + len(b.lpns)
+	b.lpns = append(b.lpns, lpn)
+	b.valid++
+	b.written = f.clock
+	f.stats.FlashWrites++
+}
+
+// collect copies the valid pages of a victim block and erases it. The victim
+// has at least one invalid page, so the copies never need more than the one
+// free block held in reserve.
+func (f *FTL) collect() error {
+	victim := f.victim()
+	if victim < 0 {
+		return ErrFull
+	}
+
+	lpns := f.blocks[victim].lpns
+	for _, lpn := range lpns {
+		if lpn < 0 {
+			continue
+		}
+		if f.full(f.gcActive) {
+			f.gcActive = f.take()
+		}
+		f.program(f.gcActive, lpn)
+		f.stats.GCCopies++
+	}
+
+	b := &f.blocks[victim]
+	b.lpns = lpns[:0]
+	b.valid = 0
+	b.erases++
+	f.stats.Erases++
+	f.free = append(f.free, victim)
+	return nil
+}
+
+// victim returns the full block garbage collection should reclaim, or -1
+// when no block has anything to reclaim.
+func (f *FTL) victim() int {
+	victim, best := -1, -1.0
+	for i := range f.blocks {
+		b := &f.blocks[i]
+		if i == f.active || i == f.gcActive || !f.full(i) || b.valid == f.config.PagesPerBlock {
+			continue
+		}
+		u := float64(b.valid) / float64(f.config.PagesPerBlock)
+		var score float64
+		if f.config.GC == Greedy {
+			score = 1 - u
+		} else {
+			// (1-u)*age/2u, with empty blocks always worth taking
+			score = math.Inf(1)
+			if b.valid > 0 {
+				score = (1 - u) * float64(f.clock-b.written+1) / (2 * u)
+			}
+		}
+		if score > best {
+			victim, best = i, score
+		}
+	}
+	return victim
+}
+
+// GCCopies returns the number of pages garbage collection has moved so far.
+func (f *FTL) GCCopies() int {
+	return f.stats.GCCopies
+}
+
+// Erases returns the number of blocks erased so far.
+func (f *FTL) Erases() int {
+	return f.stats.Erases
+}
+
+func (f *FTL) Stats() Stats {
+	stats := f.stats
+	if stats.HostWrites > 0 {
+		stats.WriteAmplification = float64(stats.FlashWrites) / float64(stats.HostWrites)
+	}
+	stats.BlockErases = make([]int, len(f.blocks))
+	for i, b := range f.blocks {
+		stats.BlockErases[i] = b.erases
+	}
+	return stats
+}
@@ -0,0 +1,97 @@
+package ftl
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func newTestFTL(t *testing.T, gc string) *FTL {
+	t.Helper()
+	f, err := New(Config{LogicalPages: 1000, PagesPerBlock: 32, OverProvisioning: 0.07, GC: gc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestSequentialWritesNeedNoGC(t *testing.T) {
+	f := newTestFTL(t, Greedy)
+	for lpn := 0; lpn < 1000; lpn++ {
+		if err := f.Write(lpn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := f.Stats()
+	if stats.HostWrites != 1000 || stats.FlashWrites != 1000 || stats.GCCopies != 0 || stats.Erases != 0 {
+		t.Errorf("got %+v", stats)
+	}
+	if stats.WriteAmplification != 1 {
+		t.Errorf("write amplification %v, want 1", stats.WriteAmplification)
+	}
+}
+
+// Random overwrites of a full device keep the books balanced under both
+// garbage collection policies.
+func TestRandomOverwrites(t *testing.T) {
+	for _, gc := range []string{Greedy, CostBenefit} {
+		f := newTestFTL(t, gc)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 50000; i++ {
+			lpn := i
+			if i >= 1000 {
+				lpn = r.Intn(1000)
+			}
+			if err := f.Write(lpn); err != nil {
+				t.Fatalf("%v: write %d: %v", gc, i, err)
+			}
+		}
+
+		stats := f.Stats()
+		if stats.FlashWrites != stats.HostWrites+stats.GCCopies {
+			t.Errorf("%v: %d flash writes, %d host writes and %d copies", gc, stats.FlashWrites, stats.HostWrites, stats.GCCopies)
+		}
+		if stats.Erases == 0 || stats.WriteAmplification <= 1 {
+			t.Errorf("%v: %d erases, write amplification %v", gc, stats.Erases, stats.WriteAmplification)
+		}
+		erases := 0
+		for _, n := range stats.BlockErases {
+			erases += n
+		}
+		if erases != stats.Erases {
+			t.Errorf("%v: blocks erased %d times in all, %d erases counted", gc, erases, stats.Erases)
+		}
+		if len(f.mapping) != 1000 {
+			t.Errorf("%v: %d pages mapped, want 1000", gc, len(f.mapping))
+		}
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	f := newTestFTL(t, Greedy)
+	for lpn := 0; lpn < 1000; lpn++ {
+		if err := f.Write(lpn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Write(1000); err == nil {
+		t.Error("wrote a page past the logical capacity")
+	}
+	// a trimmed page makes room for another one
+	f.Trim(0)
+	if err := f.Write(1000); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{LogicalPages: 0, PagesPerBlock: 32, GC: Greedy},
+		{LogicalPages: 10, PagesPerBlock: 0, GC: Greedy},
+		{LogicalPages: 10, PagesPerBlock: 32, OverProvisioning: -1, GC: Greedy},
+		{LogicalPages: 10, PagesPerBlock: 32, GC: "fifo"},
+	} {
+		if _, err := New(config); err == nil {
+			t.Errorf("accepted %+v", config)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"golang/tracegen"
+	"io"
+	"log"
+	"os"
+)
+
+// runGenerate implements the generate subcommand: a synthetic trace in the
+// addr,op format, written to stdout or to the file given with -o.
+func runGenerate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	length := flags.Int("n", 100000, "number of accesses shared by the phases without an explicit length")
+	writeFraction := flags.Float64("write", 0.3, "fraction of the accesses that are writes")
+	seed := flags.Int64("seed", 1, "seed of the random generator; the same seed gives the same trace")
+	outPath := flags.String("o", "", "write the trace to this file instead of stdout")
+	flags.Usage = func() {
+		fmt.Println("program generate [flags] <workload>")
+		fmt.Println(`workload: phases separated by ";", each "[length:]pattern" or a mixture "w*pattern+w*pattern..."`)
+		fmt.Println("pattern: uniform(blocks[,offset]), zipf(blocks,skew[,offset]), scan([offset]), loop(blocks[,offset])")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *length < 0 || *writeFraction < 0 || *writeFraction > 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	phases, err := tracegen.Parse(flags.Arg(0), *length)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *outPath != "" {
+		if out, err = os.Create(*outPath); err != nil {
+			log.Fatal(err.Error())
+		}
+		defer out.Close()
+	}
+
+	writer := bufio.NewWriter(out)
+	generator := tracegen.NewGenerator(phases, *writeFraction, *seed)
+	for {
+		trace, err := generator.Next()
+		if err == io.EOF {
+			break
+		}
+		fmt.Fprintf(writer, "%d,%v\n", trace.Addr, trace.Op)
+	}
+	if err = writer.Flush(); err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -1,6 +1,6 @@
 module golang
 
-go 1.16
+go 1.18
 
 require (
 	github.com/petar/GoLLRB v0.0.0-20210522233825-ae3b015fd3e9
@@ -0,0 +1,213 @@
+package latency
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+
+	"golang/simulator"
+)
+
+type Config struct {
+	Hit     time.Duration
+	Read    time.Duration
+	Program time.Duration
+	Erase   time.Duration
+	// QueueDepth is the number of requests the host keeps in flight; each
+	// one is served independently, so it shortens the elapsed time but not
+	// the latency of a request.
+	QueueDepth int
+}
+
+// Flash reports the garbage collection work done so far by an SSD model, so
+// it can be charged to the access that triggered it.
+type Flash interface {
+	GCCopies() int
+	Erases() int
+}
+
+type Stats struct {
+	Accesses    int     `json:"accesses"`
+	MeanLatency float64 `json:"mean_latency_us"`
+	// P99Latency is read from a histogram and may exceed the exact
+	// percentile by up to 1/64 of it.
+	P99Latency float64 `json:"p99_latency_us"`
+	MaxLatency float64 `json:"max_latency_us"`
+	// IOTime is the sum of the service times of every operation, flushes
+	// included; Elapsed spreads it over the queue depth.
+	IOTime  float64 `json:"io_time_seconds"`
+	Elapsed float64 `json:"elapsed_seconds"`
+	IOPS    float64 `json:"iops"`
+}
+
+// Model turns the events of a simulator into service times. A read miss
+// reads the page from flash, every page written back is programmed, and the
+// copies and erases of garbage collection are charged to the access during
+// which they happen. Every access pays the DRAM latency.
+type Model struct {
+	config    Config
+	flash     Flash
+	gcCopies  int
+	erases    int
+	op        simulator.Op
+	started   bool
+	current   time.Duration
+	latencies histogram
+	total     time.Duration
+	slots     []time.Duration
+}
+
+func New(config Config) (*Model, error) {
+	if config.Hit < 0 || config.Read < 0 || config.Program < 0 || config.Erase < 0 || config.QueueDepth < 1 {
+		return nil, fmt.Errorf("invalid timing model: hit %v, read %v, program %v, erase %v, queue depth %d",
+			config.Hit, config.Read, config.Program, config.Erase, config.QueueDepth)
+	}
+	return &Model{config: config, slots: make([]time.Duration, config.QueueDepth)}, nil
+}
+
+// SetFlash makes the model charge the garbage collection of flash. The
+// model must observe the events after flash does.
+func (m *Model) SetFlash(flash Flash) {
+	m.flash = flash
+}
+
+// Begin starts a new access of the given operation and closes the previous
+// one.
+func (m *Model) Begin(op simulator.Op) {
+	m.end()
+	m.started = true
+	m.op = op
+	m.current = 0
+	if op == simulator.OpRead || op == simulator.OpWrite {
+		m.current = m.config.Hit
+	}
+}
+
+func (m *Model) Observe(e simulator.Event) {
+	switch {
+	case e.Kind == simulator.EventMiss && m.op == simulator.OpRead:
+		m.current += m.config.Read
+	case e.Kind.IsWriteBack():
+		m.current += m.config.Program
+	}
+
+	if m.flash != nil {
+		copies, erases := m.flash.GCCopies(), m.flash.Erases()
+		m.current += time.Duration(copies-m.gcCopies) * (m.config.Read + m.config.Program)
+		m.current += time.Duration(erases-m.erases) * m.config.Erase
+		m.gcCopies, m.erases = copies, erases
+	}
+}
+
+func (m *Model) end() {
+	if !m.started {
+		return
+	}
+	if m.op == simulator.OpRead || m.op == simulator.OpWrite {
+		m.latencies.add(m.current)
+	}
+	m.total += m.current
+
+	// the request goes to the first slot of the queue to become free
+	first := 0
+	for i, t := range m.slots {
+		if t < m.slots[first] {
+			first = i
+		}
+	}
+	m.slots[first] += m.current
+
+	m.started = false
+}
+
+// Stats closes the access in progress and summarizes every access so far.
+func (m *Model) Stats() Stats {
+	m.end()
+
+	stats := Stats{Accesses: m.latencies.count, IOTime: m.total.Seconds()}
+	for _, t := range m.slots {
+		if t.Seconds() > stats.Elapsed {
+			stats.Elapsed = t.Seconds()
+		}
+	}
+	if stats.Elapsed > 0 {
+		stats.IOPS = float64(stats.Accesses) / stats.Elapsed
+	}
+	if m.latencies.count == 0 {
+		return stats
+	}
+
+	stats.MeanLatency = microseconds(m.latencies.sum) / float64(m.latencies.count)
+	stats.P99Latency = microseconds(m.latencies.quantile(0.99))
+	stats.MaxLatency = microseconds(m.latencies.max)
+	return stats
+}
+
+// subBits sets the resolution of a histogram: values under 1<<subBits are
+// counted exactly, larger ones in buckets 1/(1<<(subBits-1)) of their value
+// wide.
+const subBits = 7
+
+// histogram counts latencies in buckets that widen with the value, so a
+// quantile is read within 1/64 of its value in constant memory however many
+// accesses a trace has. The sum and the maximum are kept exactly.
+type histogram struct {
+	buckets [(1 << subBits) + (64-subBits)*(1<<(subBits-1))]int
+	count   int
+	sum     time.Duration
+	max     time.Duration
+}
+
+func (h *histogram) add(d time.Duration) {
+	h.buckets[bucket(d)]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// bucket returns the index of the bucket counting d. Past the exact buckets,
+// each power of two is split in 1<<(subBits-1) buckets by the bits of d that
+// follow its leading one.
+func bucket(d time.Duration) int {
+	v := uint64(d)
+	if v < 1<<subBits {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBits
+	return 1<<subBits + (shift-1)<<(subBits-1) + int(v>>shift) - 1<<(subBits-1)
+}
+
+// upperBound returns the largest value counted by bucket i.
+func upperBound(i int) time.Duration {
+	if i < 1<<subBits {
+		return time.Duration(i)
+	}
+	i -= 1 << subBits
+	shift := i>>(subBits-1) + 1
+	top := uint64(i&(1<<(subBits-1)-1) + 1<<(subBits-1))
+	return time.Duration((top+1)<<shift - 1)
+}
+
+// quantile returns the upper bound of the bucket holding the q-quantile,
+// capped at the maximum, so it may overestimate but never underestimates.
+func (h *histogram) quantile(q float64) time.Duration {
+	rank := int(math.Ceil(q * float64(h.count)))
+	seen := 0
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			if bound := upperBound(i); bound < h.max {
+				return bound
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
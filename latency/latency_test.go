@@ -0,0 +1,38 @@
+package latency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketBounds(t *testing.T) {
+	for _, d := range []time.Duration{0, 1, 127, 128, 129, 255, 256, 25 * time.Microsecond, time.Second, 1<<62 + 12345} {
+		i := bucket(d)
+		if upperBound(i) < d {
+			t.Errorf("%d: bucket %d ends at %d", d, i, upperBound(i))
+		}
+		if i > 0 && upperBound(i-1) >= d {
+			t.Errorf("%d: bucket %d before it ends at %d", d, i-1, upperBound(i-1))
+		}
+		if over := upperBound(i) - d; over > d/64 {
+			t.Errorf("%d: bucket %d overshoots by %d", d, i, over)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	var h histogram
+	for i := 1; i <= 1000; i++ {
+		h.add(time.Duration(i) * time.Microsecond)
+	}
+	want := 990 * time.Microsecond
+	if got := h.quantile(0.99); got < want || got > want+want/64 {
+		t.Errorf("p99 %v, want %v within 1/64", got, want)
+	}
+	if got := h.quantile(1); got != 1000*time.Microsecond {
+		t.Errorf("p100 %v, want the maximum", got)
+	}
+	if h.sum != 500500*time.Microsecond {
+		t.Errorf("sum %v", h.sum)
+	}
+}
@@ -0,0 +1,250 @@
+package lirs
+
+const (
+	stackList = iota
+	queueList
+	ghostList
+)
+
+// entry is linked into the LIRS stack, the queue of resident HIR blocks and
+// the queue of non-resident HIR blocks, depending on its state.
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	lir      bool
+	resident bool
+	prev     [3]*entry[K, V]
+	next     [3]*entry[K, V]
+}
+
+// ring is a circular doubly linked list threaded through one of the link
+// slots of its entries. The front is the oldest entry.
+type ring[K comparable, V any] struct {
+	slot int
+	root entry[K, V]
+	len  int
+}
+
+func (r *ring[K, V]) init(slot int) {
+	r.slot = slot
+	r.root.prev[slot] = &r.root
+	r.root.next[slot] = &r.root
+}
+
+func (r *ring[K, V]) contains(e *entry[K, V]) bool {
+	return e.next[r.slot] != nil
+}
+
+func (r *ring[K, V]) front() *entry[K, V] {
+	if r.len == 0 {
+		return nil
+	}
+	return r.root.next[r.slot]
+}
+
+func (r *ring[K, V]) pushBack(e *entry[K, V]) {
+	if r.contains(e) {
+		r.remove(e)
+	}
+	last := r.root.prev[r.slot]
+	e.prev[r.slot] = last
+	e.next[r.slot] = &r.root
+	last.next[r.slot] = e
+	r.root.prev[r.slot] = e
+	r.len++
+}
+
+func (r *ring[K, V]) remove(e *entry[K, V]) {
+	if !r.contains(e) {
+		return
+	}
+	e.prev[r.slot].next[r.slot] = e.next[r.slot]
+	e.next[r.slot].prev[r.slot] = e.prev[r.slot]
+	e.prev[r.slot] = nil
+	e.next[r.slot] = nil
+	r.len--
+}
+
+// Cache is a fixed-capacity key-value cache replaced with the LIRS policy,
+// the same one LIRS simulates. One percent of the capacity, and at least one
+// entry, holds resident HIR entries; the rest holds LIR entries. The history
+// of evicted entries kept to recognize their reuse is bounded by the
+// capacity.
+//
+// A Cache is not safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	lirCapacity int
+	hirCapacity int
+	lirCount    int
+	entries     map[K]*entry[K, V]
+	stack       ring[K, V]
+	queue       ring[K, V]
+	ghosts      ring[K, V]
+	onEvict     func(key K, value V)
+}
+
+// NewCache returns an empty cache holding up to capacity entries, which must
+// be at least 2. onEvict, if not nil, is called with every entry the cache
+// evicts to make room, but not with those removed by Delete.
+func NewCache[K comparable, V any](capacity int, onEvict func(key K, value V)) *Cache[K, V] {
+	if capacity < 2 {
+		panic("lirs: cache capacity must be at least 2")
+	}
+	hirCapacity := capacity / 100
+	if hirCapacity < 1 {
+		hirCapacity = 1
+	}
+	c := &Cache[K, V]{
+		lirCapacity: capacity - hirCapacity,
+		hirCapacity: hirCapacity,
+		entries:     make(map[K]*entry[K, V], capacity),
+		onEvict:     onEvict,
+	}
+	c.stack.init(stackList)
+	c.queue.init(queueList)
+	c.ghosts.init(ghostList)
+	return c
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache[K, V]) Len() int {
+	return c.lirCount + c.queue.len
+}
+
+// Get returns the value stored under key and records the access.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.entries[key]
+	if !ok || !e.resident {
+		return value, false
+	}
+	c.hit(e)
+	return e.value, true
+}
+
+// Set stores value under key, evicting an entry if the cache is full.
+func (c *Cache[K, V]) Set(key K, value V) {
+	if e, ok := c.entries[key]; ok && e.resident {
+		e.value = value
+		c.hit(e)
+		return
+	}
+
+	e, ok := c.entries[key]
+	if !ok {
+		e = &entry[K, V]{key: key}
+		c.entries[key] = e
+	}
+	c.ghosts.remove(e)
+	e.value = value
+	e.resident = true
+
+	if c.lirCount < c.lirCapacity {
+		// LIR space left: every new entry is LIR
+		c.stack.pushBack(e)
+		c.makeLIR(e)
+		return
+	}
+
+	if c.queue.len == c.hirCapacity {
+		c.evict()
+	}
+	if c.stack.contains(e) {
+		// evicted recently enough to still be in the stack: its reuse
+		// distance beats the oldest LIR entry
+		c.makeLIR(e)
+		c.stack.pushBack(e)
+		c.demoteBottom()
+		return
+	}
+	c.stack.pushBack(e)
+	c.queue.pushBack(e)
+}
+
+// Delete removes key from the cache and forgets its history.
+func (c *Cache[K, V]) Delete(key K) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	if e.lir {
+		c.lirCount--
+	}
+	c.stack.remove(e)
+	c.queue.remove(e)
+	c.ghosts.remove(e)
+	delete(c.entries, key)
+	c.prune()
+}
+
+func (c *Cache[K, V]) hit(e *entry[K, V]) {
+	switch {
+	case e.lir:
+		c.stack.pushBack(e)
+		c.prune()
+	case c.stack.contains(e):
+		c.queue.remove(e)
+		c.makeLIR(e)
+		c.stack.pushBack(e)
+		if c.lirCount > c.lirCapacity {
+			c.demoteBottom()
+		}
+	default:
+		c.stack.pushBack(e)
+		c.queue.pushBack(e)
+	}
+}
+
+func (c *Cache[K, V]) makeLIR(e *entry[K, V]) {
+	e.lir = true
+	c.lirCount++
+}
+
+// evict drops the oldest resident HIR entry. Its key stays in the stack, if
+// it is there, to detect a reuse.
+func (c *Cache[K, V]) evict() {
+	e := c.queue.front()
+	c.queue.remove(e)
+	e.resident = false
+	key, value := e.key, e.value
+	var zero V
+	e.value = zero
+
+	if c.stack.contains(e) {
+		c.ghosts.pushBack(e)
+		if c.ghosts.len > c.lirCapacity+c.hirCapacity {
+			old := c.ghosts.front()
+			c.ghosts.remove(old)
+			c.stack.remove(old)
+			delete(c.entries, old.key)
+		}
+	} else {
+		delete(c.entries, key)
+	}
+
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+}
+
+// demoteBottom turns the LIR entry at the bottom of the stack into a
+// resident HIR entry, making room for an entry just promoted to LIR.
+func (c *Cache[K, V]) demoteBottom() {
+	bottom := c.stack.front()
+	c.stack.remove(bottom)
+	bottom.lir = false
+	c.lirCount--
+	c.queue.pushBack(bottom)
+	c.prune()
+}
+
+// prune removes HIR entries from the bottom of the stack until an LIR entry
+// is there, forgetting the non-resident ones.
+func (c *Cache[K, V]) prune() {
+	for e := c.stack.front(); e != nil && !e.lir; e = c.stack.front() {
+		c.stack.remove(e)
+		if !e.resident {
+			c.ghosts.remove(e)
+			delete(c.entries, e.key)
+		}
+	}
+}
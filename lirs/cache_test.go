@@ -0,0 +1,97 @@
+package lirs
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCacheLenWithinCapacity(t *testing.T) {
+	for _, capacity := range []int{2, 3, 10, 150} {
+		c := NewCache[int, int](capacity, nil)
+		r := rand.New(rand.NewSource(int64(capacity)))
+		for i := 0; i < 20000; i++ {
+			key := r.Intn(4 * capacity)
+			switch r.Intn(4) {
+			case 0:
+				c.Get(key)
+			case 1:
+				c.Delete(key)
+			default:
+				c.Set(key, key)
+			}
+			if c.Len() > capacity {
+				t.Fatalf("capacity %d: Len %d after %d operations", capacity, c.Len(), i+1)
+			}
+		}
+	}
+}
+
+// Every entry evicted is reported once with the value it held, and is gone.
+func TestCacheOnEvict(t *testing.T) {
+	const capacity = 20
+	stored := make(map[int]int)
+	var c *Cache[int, int]
+	c = NewCache[int, int](capacity, func(key, value int) {
+		if want, ok := stored[key]; !ok || value != want {
+			t.Errorf("evicted %d with %d, stored %d (%v)", key, value, want, ok)
+		}
+		delete(stored, key)
+	})
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		key := r.Intn(3 * capacity)
+		if r.Intn(2) == 0 {
+			c.Set(key, i)
+			stored[key] = i
+		} else if value, ok := c.Get(key); ok && value != stored[key] {
+			t.Fatalf("Get(%d) = %d, want %d", key, value, stored[key])
+		}
+	}
+	if c.Len() != len(stored) {
+		t.Errorf("Len %d, but %d entries stored and not evicted", c.Len(), len(stored))
+	}
+	for key := range stored {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("key %d neither evicted nor cached", key)
+		}
+	}
+}
+
+// Deleting a key whose entry is only a ghost forgets its history without
+// touching the resident entries.
+func TestCacheDeleteGhost(t *testing.T) {
+	const capacity = 10
+	evicted := 0
+	c := NewCache[int, int](capacity, func(int, int) { evicted++ })
+	for key := 0; key < 2*capacity; key++ {
+		c.Set(key, key)
+	}
+	if c.ghosts.len == 0 {
+		t.Fatal("no ghost entries to delete")
+	}
+	ghost, ghosts := c.ghosts.front().key, c.ghosts.len
+
+	c.Delete(ghost)
+	if c.Len() != capacity {
+		t.Errorf("Len %d after deleting a ghost, want %d", c.Len(), capacity)
+	}
+	if _, ok := c.entries[ghost]; ok {
+		t.Errorf("ghost %d still known after Delete", ghost)
+	}
+	if c.ghosts.len != ghosts-1 {
+		t.Errorf("%d ghosts after deleting one of %d", c.ghosts.len, ghosts)
+	}
+
+	// the ghost comes back as a new HIR entry rather than being promoted
+	c.Set(ghost, -1)
+	if e := c.entries[ghost]; e == nil || e.lir {
+		t.Errorf("deleted ghost %d came back as LIR", ghost)
+	}
+	if value, ok := c.Get(ghost); !ok || value != -1 {
+		t.Errorf("Get(%d) = %d, %v", ghost, value, ok)
+	}
+	if c.Len() > capacity {
+		t.Errorf("Len %d exceeds %d", c.Len(), capacity)
+	}
+}
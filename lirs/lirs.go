@@ -4,8 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
-	"time"
 
 	"golang/simulator"
 	// "github.com/esaiy/golang-lirs/simulator"
@@ -18,103 +16,152 @@ type LIRS struct {
 	HIRSize      int
 	hit          int
 	miss         int
-	writeCount   int
+	readHit      int
+	readMiss     int
+	writeHit     int
+	writeBack    *simulator.WriteBack
 	orderedStack *orderedmap.OrderedMap
 	orderedList  *orderedmap.OrderedMap
 	LIR          map[interface{}]int
 	HIR          map[interface{}]int
 	// cache        map[interface{}]bool
+
+	simulator.Notifier
 }
 
+// NewLIRS builds a cache of cacheSize blocks, HIRSize percent of them for
+// resident HIR blocks. At least one block is kept for HIR blocks, so a cache
+// under 100 blocks can still admit new ones, and LIR blocks get all the rest.
+// cacheSize must be at least 2.
 func NewLIRS(cacheSize, HIRSize int) *LIRS {
 	if HIRSize > 100 || HIRSize < 0 {
 		log.Fatal("HIRSize must be between 0 and 100")
 	}
-	LIRCapacity := (100 - HIRSize) * cacheSize / 100
 	HIRCapacity := HIRSize * cacheSize / 100
-	return &LIRS{
+	if HIRSize > 0 && HIRCapacity < 1 {
+		HIRCapacity = 1
+	}
+	LIRCapacity := cacheSize - HIRCapacity
+	LIRSObject := &LIRS{
 		cacheSize:    cacheSize,
 		LIRSize:      LIRCapacity,
 		HIRSize:      HIRCapacity,
 		hit:          0,
 		miss:         0,
-		writeCount:   0,
 		orderedStack: orderedmap.NewOrderedMap(),
 		orderedList:  orderedmap.NewOrderedMap(),
 		LIR:          make(map[interface{}]int, LIRCapacity),
 		HIR:          make(map[interface{}]int, HIRCapacity),
 		// cache:        make(map[interface{}]bool, cacheSize),
 	}
+	LIRSObject.writeBack = simulator.NewWriteBack(&LIRSObject.Notifier)
+	return LIRSObject
 }
 
 func (LIRSObject *LIRS) Get(trace simulator.Trace) (err error) {
 	block := trace.Addr
 	op := trace.Op
-	// if op == "W" {
-	// 	LIRSObject.writeCount++
-	// }
+	switch op {
+	case simulator.OpTrim:
+		LIRSObject.Trim(block)
+		return nil
+	case simulator.OpFlush:
+		LIRSObject.writeBack.Flush()
+		return nil
+	}
+	// every accessed block ends up resident, either LIR or HIR in the list
+	defer LIRSObject.writeBack.Access(block, op)
 
 	if len(LIRSObject.LIR) < LIRSObject.LIRSize {
 		// LIR is not full; there is space in cache
 		LIRSObject.miss += 1
-		// Tambahan
-		LIRSObject.writeCount++
 		if _, ok := LIRSObject.LIR[block]; ok {
 			// block is in LIR, not a miss
 			LIRSObject.miss -= 1
 			LIRSObject.hit += 1
-			// Tambahan
-			LIRSObject.writeCount--
+			LIRSObject.countHit(op)
+			LIRSObject.Notify(simulator.EventHitLIR, block)
+		} else if _, ok := LIRSObject.orderedList.Get(block); ok {
+			// block is HIR resident, left over from before a trim freed
+			// LIR space: not a miss either
+			LIRSObject.miss -= 1
+			LIRSObject.hit += 1
+			LIRSObject.countHit(op)
+			LIRSObject.Notify(simulator.EventHitResidentHIR, block)
+		} else {
+			LIRSObject.countMiss(op)
+			LIRSObject.Notify(simulator.EventMiss, block)
 		}
 		LIRSObject.addToStack(block)
 		LIRSObject.makeLIR(block)
+		// the block may have left HIR blocks, kept by a trim, at the bottom
+		LIRSObject.pruneStack()
 		return nil
 	}
 
 	if _, ok := LIRSObject.LIR[block]; ok {
 		// hit, block is in LIR
 		LIRSObject.handleLIRBlock(block)
-		// Tambahan 2
-		if op == "W" {
-			LIRSObject.writeCount++
-		}
+		LIRSObject.countHit(op)
 	} else if _, ok := LIRSObject.orderedList.Get(block); ok {
 		// hit, block is HIR resident
 		LIRSObject.handleHIRResidentBlock(block)
-		// Tambahan 2
-		if op == "W" {
-			LIRSObject.writeCount++
-		}
+		LIRSObject.countHit(op)
 	} else {
 		// miss, blok is HIR non resident
 		LIRSObject.handleHIRNonResidentBlock(block)
+		LIRSObject.countMiss(op)
 	}
 	return nil
 }
 
-func (LIRSObject *LIRS) PrintToFile(file *os.File, start time.Time) (err error) {
-	duration := time.Since(start)
-	hitRatio := 100 * float32(float32(LIRSObject.hit)/float32(LIRSObject.hit+LIRSObject.miss))
-	result := fmt.Sprintf(`_______________________________________________________
-LIRS
-cache size : %v
-cache hit : %v
-cache miss : %v
-hit ratio : %v
-list size : %v
-stack size : %v
-lir capacity: %v
-hir capacity: %v
-write count : %v
-duration : %v
-!LIRS|%v|%v|%v
-`, LIRSObject.cacheSize, LIRSObject.hit, LIRSObject.miss, hitRatio, LIRSObject.orderedList.Len(), LIRSObject.orderedStack.Len(), LIRSObject.LIRSize, LIRSObject.HIRSize, LIRSObject.writeCount, duration.Seconds(), LIRSObject.cacheSize, LIRSObject.hit, LIRSObject.hit+LIRSObject.miss)
-	_, err = file.WriteString(result)
-	return err
+func (LIRSObject *LIRS) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      LIRSObject.cacheSize,
+		Hits:           LIRSObject.hit,
+		Misses:         LIRSObject.miss,
+		ReadHits:       LIRSObject.readHit,
+		ReadMisses:     LIRSObject.readMiss,
+		WriteHits:      LIRSObject.writeHit,
+		DirtyEvictions: LIRSObject.writeBack.DirtyEvictions,
+		CleanEvictions: LIRSObject.writeBack.CleanEvictions,
+		FlashWrites:    LIRSObject.writeBack.FlashWrites,
+		ResidentSize:   len(LIRSObject.LIR) + LIRSObject.orderedList.Len(),
+		StackSize:      LIRSObject.orderedStack.Len(),
+		ListSize:       LIRSObject.orderedList.Len(),
+	}
+}
+
+// Trim forgets block entirely: a resident copy is dropped without counting a
+// write, and its history in the stack is discarded.
+func (LIRSObject *LIRS) Trim(block int) {
+	LIRSObject.writeBack.Discard(block)
+	delete(LIRSObject.LIR, block)
+	delete(LIRSObject.HIR, block)
+	LIRSObject.orderedList.Delete(block)
+	LIRSObject.orderedStack.Delete(block)
+	LIRSObject.pruneStack()
+}
+
+// pruneStack pops HIR blocks off the bottom of the stack until an LIR block is
+// there.
+func (LIRSObject *LIRS) pruneStack() {
+	for {
+		key, _, ok := LIRSObject.orderedStack.GetFirst()
+		if !ok {
+			return
+		}
+		if _, isLIR := LIRSObject.LIR[key]; isLIR {
+			return
+		}
+		LIRSObject.orderedStack.PopFirst()
+		LIRSObject.Notify(simulator.EventPrunedFromStack, key.(int))
+	}
 }
 
 func (LIRSObject *LIRS) handleLIRBlock(block int) (err error) {
 	LIRSObject.hit += 1
+	LIRSObject.Notify(simulator.EventHitLIR, block)
 	key, _, ok := LIRSObject.orderedStack.GetFirst()
 	if !ok {
 		return errors.New("orderedStack is empty")
@@ -130,7 +177,11 @@ func (LIRSObject *LIRS) handleLIRBlock(block int) (err error) {
 
 func (LIRSObject *LIRS) handleHIRResidentBlock(block int) {
 	LIRSObject.hit += 1
-	if _, ok := LIRSObject.orderedStack.Get(block); ok {
+	LIRSObject.Notify(simulator.EventHitResidentHIR, block)
+	_, inStack := LIRSObject.orderedStack.Get(block)
+	// move block to the top first, so it cannot stop the pruning where it was
+	LIRSObject.addToStack(block)
+	if inStack {
 		// block is in stack, move to LIR
 		LIRSObject.makeLIR(block)
 		LIRSObject.removeFromList(block)
@@ -139,15 +190,15 @@ func (LIRSObject *LIRS) handleHIRResidentBlock(block int) {
 		// block is not in stack, move to end of list
 		LIRSObject.orderedList.MoveLast(block)
 	}
-	LIRSObject.addToStack(block)
 }
 
 func (LIRSObject *LIRS) handleHIRNonResidentBlock(block int) {
 	LIRSObject.miss += 1
-	// Tambahan
-	LIRSObject.writeCount++
+	LIRSObject.Notify(simulator.EventMiss, block)
 	LIRSObject.addToList(block)
-	if _, ok := LIRSObject.orderedStack.Get(block); ok {
+	_, inStack := LIRSObject.orderedStack.Get(block)
+	LIRSObject.addToStack(block)
+	if inStack {
 		// block is in stack, move to LIR
 		LIRSObject.makeLIR(block)
 		LIRSObject.removeFromList(block)
@@ -155,7 +206,20 @@ func (LIRSObject *LIRS) handleHIRNonResidentBlock(block int) {
 	} else {
 		LIRSObject.makeHIR(block)
 	}
-	LIRSObject.addToStack(block)
+}
+
+func (LIRSObject *LIRS) countHit(op simulator.Op) {
+	if op == simulator.OpWrite {
+		LIRSObject.writeHit++
+	} else {
+		LIRSObject.readHit++
+	}
+}
+
+func (LIRSObject *LIRS) countMiss(op simulator.Op) {
+	if op == simulator.OpRead {
+		LIRSObject.readMiss++
+	}
 }
 
 func (LIRSObject *LIRS) addToStack(block int) {
@@ -168,7 +232,9 @@ func (LIRSObject *LIRS) addToStack(block int) {
 
 func (LIRSObject *LIRS) addToList(block int) {
 	if LIRSObject.orderedList.Len() == LIRSObject.HIRSize {
-		LIRSObject.orderedList.PopFirst()
+		if key, _, ok := LIRSObject.orderedList.PopFirst(); ok {
+			LIRSObject.writeBack.Evict(key.(int))
+		}
 	}
 	LIRSObject.orderedList.Set(block, 1)
 }
@@ -178,12 +244,18 @@ func (LIRSObject *LIRS) removeFromList(block int) {
 }
 
 func (LIRSObject *LIRS) makeLIR(block int) {
+	if _, ok := LIRSObject.LIR[block]; !ok {
+		LIRSObject.Notify(simulator.EventPromotedToLIR, block)
+	}
 	LIRSObject.LIR[block] = 1
 	LIRSObject.removeFromList(block)
 	delete(LIRSObject.HIR, block)
 }
 
 func (LIRSObject *LIRS) makeHIR(block int) {
+	if _, ok := LIRSObject.LIR[block]; ok {
+		LIRSObject.Notify(simulator.EventDemotedToHIR, block)
+	}
 	LIRSObject.HIR[block] = 1
 	delete(LIRSObject.LIR, block)
 }
@@ -194,6 +266,7 @@ func (LIRSObject *LIRS) stackPrunning(removeLIR bool) (err error) {
 		return errors.New("orderedStack is empty")
 	}
 	if removeLIR {
+		LIRSObject.Notify(simulator.EventPrunedFromStack, key.(int))
 		LIRSObject.makeHIR(key.(int))
 		LIRSObject.orderedList.Set(key, 1)
 		LIRSObject.orderedList.MoveLast(key)
@@ -205,6 +278,41 @@ func (LIRSObject *LIRS) stackPrunning(removeLIR bool) (err error) {
 			break
 		}
 		LIRSObject.orderedStack.PopFirst()
+		LIRSObject.Notify(simulator.EventPrunedFromStack, k.(int))
+	}
+	return nil
+}
+
+// CheckInvariants verifies that the stack, the list and the LIR/HIR maps agree
+// with each other and returns the first inconsistency found.
+func (LIRSObject *LIRS) CheckInvariants() error {
+	if len(LIRSObject.LIR) > LIRSObject.LIRSize {
+		return fmt.Errorf("%d LIR blocks exceed the LIR capacity of %d", len(LIRSObject.LIR), LIRSObject.LIRSize)
+	}
+	if LIRSObject.orderedList.Len() > LIRSObject.HIRSize {
+		return fmt.Errorf("%d resident HIR blocks exceed the HIR capacity of %d", LIRSObject.orderedList.Len(), LIRSObject.HIRSize)
+	}
+	if key, _, ok := LIRSObject.orderedStack.GetFirst(); ok {
+		if _, isLIR := LIRSObject.LIR[key]; !isLIR {
+			return fmt.Errorf("block %v at the bottom of the stack is not LIR", key)
+		}
+	}
+	for block := range LIRSObject.LIR {
+		if _, ok := LIRSObject.HIR[block]; ok {
+			return fmt.Errorf("block %v is both LIR and HIR", block)
+		}
+		if _, ok := LIRSObject.orderedStack.Get(block); !ok {
+			return fmt.Errorf("LIR block %v is not in the stack", block)
+		}
+	}
+	iter := LIRSObject.orderedList.Iter()
+	for block, _, ok := iter.Next(); ok; block, _, ok = iter.Next() {
+		if _, isLIR := LIRSObject.LIR[block]; isLIR {
+			return fmt.Errorf("list entry %v is an LIR block", block)
+		}
+		if _, isHIR := LIRSObject.HIR[block]; !isHIR {
+			return fmt.Errorf("list entry %v is not a resident HIR block", block)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,11 @@
+package lirs
+
+import (
+	"testing"
+
+	"golang/simulator/simtest"
+)
+
+func TestHits(t *testing.T) {
+	simtest.RunHitTests(t, func() simtest.Simulator { return NewLIRS(200, 1) }, simtest.LIRSHitTests)
+}
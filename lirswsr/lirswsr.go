@@ -4,8 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
-	"time"
 
 	"golang/simulator"
 
@@ -14,11 +12,8 @@ import (
 
 type (
 	BlockInfo struct {
-		Address   int
-		Operation string
-		DirtyPage bool
-		ColdFlag  bool
-		access    int
+		ColdFlag bool
+		access   int
 	}
 	LIRSWSR struct {
 		cacheSize    int
@@ -26,42 +21,60 @@ type (
 		HIRSize      int
 		hit          int
 		miss         int
-		writeCount   int
+		readHit      int
+		readMiss     int
+		writeHit     int
+		writeBack    *simulator.WriteBack
 		orderedStack *orderedmap.OrderedMap
 		orderedList  *orderedmap.OrderedMap
 		LIR          map[interface{}]int
 		HIR          map[interface{}]int
-		cache        map[interface{}]bool
+
+		simulator.Notifier
 	}
 )
 
+// NewLIRSWSR splits cacheSize blocks between LIR and resident HIR blocks as
+// NewLIRS does, always keeping at least one for the HIR blocks and giving the
+// rest to the LIR blocks.
 func NewLIRSWSR(cacheSize, HIRSize int) *LIRSWSR {
 	if HIRSize > 100 || HIRSize < 0 {
 		log.Fatal("HIRSize must be between 0 and 100")
 	}
-	LIRCapacity := (100 - HIRSize) * cacheSize / 100
 	HIRCapacity := HIRSize * cacheSize / 100
-	return &LIRSWSR{
+	if HIRSize > 0 && HIRCapacity < 1 {
+		HIRCapacity = 1
+	}
+	LIRCapacity := cacheSize - HIRCapacity
+	LIRSWSRObject := &LIRSWSR{
 		cacheSize:    cacheSize,
 		LIRSize:      LIRCapacity,
 		HIRSize:      HIRCapacity,
 		hit:          0,
 		miss:         0,
-		writeCount:   0,
 		orderedStack: orderedmap.NewOrderedMap(),
 		orderedList:  orderedmap.NewOrderedMap(),
 		LIR:          make(map[interface{}]int, LIRCapacity),
 		HIR:          make(map[interface{}]int, HIRCapacity),
-		cache:        make(map[interface{}]bool, cacheSize),
 	}
+	LIRSWSRObject.writeBack = simulator.NewWriteBack(&LIRSWSRObject.Notifier)
+	return LIRSWSRObject
 }
 
 func (LIRSWSRObject *LIRSWSR) Get(trace simulator.Trace) (err error) {
 	block := trace.Addr
 	op := trace.Op
-	if op == "W" {
-		LIRSWSRObject.writeCount++
+	switch op {
+	case simulator.OpTrim:
+		LIRSWSRObject.Trim(block)
+		return nil
+	case simulator.OpFlush:
+		LIRSWSRObject.writeBack.Flush()
+		return nil
 	}
+	// every accessed block ends up resident, either LIR or HIR in the list
+	defer LIRSWSRObject.writeBack.Access(block, op)
+
 	if len(LIRSWSRObject.LIR) < LIRSWSRObject.LIRSize {
 		// LIR is not full; there is space in cache
 		LIRSWSRObject.miss += 1
@@ -69,28 +82,56 @@ func (LIRSWSRObject *LIRSWSR) Get(trace simulator.Trace) (err error) {
 			// block is in LIR, not a miss
 			LIRSWSRObject.miss -= 1
 			LIRSWSRObject.hit += 1
-			LIRSWSRObject.writeCount--
+			LIRSWSRObject.countHit(op)
+			LIRSWSRObject.Notify(simulator.EventHitLIR, block)
+		} else if _, ok := LIRSWSRObject.orderedList.Get(block); ok {
+			// block is HIR resident, left over from before a trim freed
+			// LIR space: not a miss either
+			LIRSWSRObject.miss -= 1
+			LIRSWSRObject.hit += 1
+			LIRSWSRObject.countHit(op)
+			LIRSWSRObject.Notify(simulator.EventHitResidentHIR, block)
+		} else {
+			LIRSWSRObject.countMiss(op)
+			LIRSWSRObject.Notify(simulator.EventMiss, block)
 		}
-		LIRSWSRObject.addToStack(block, op)
+		LIRSWSRObject.addToStack(block)
 		LIRSWSRObject.makeLIR(block)
+		// the block may have left HIR blocks, kept by a trim, at the bottom
+		LIRSWSRObject.stackPruning()
 		return nil
 	}
 
 	if _, ok := LIRSWSRObject.LIR[block]; ok {
 		// hit, block is in LIR
 		LIRSWSRObject.handleLIRBlock(block, op)
+		LIRSWSRObject.countHit(op)
 	} else if _, ok := LIRSWSRObject.orderedList.Get(block); ok {
 		// hit, block is HIR resident
 		LIRSWSRObject.handleHIRResidentBlock(block, op)
+		LIRSWSRObject.countHit(op)
 	} else {
 		// miss, block is HIR non-resident
 		LIRSWSRObject.handleHIRNonResidentBlock(block, op)
+		LIRSWSRObject.countMiss(op)
 	}
 	return nil
 }
 
-func (LIRSWSRObject *LIRSWSR) handleLIRBlock(block int, op string) (err error) {
+// Trim forgets block entirely: a resident copy, dirty or not, is dropped
+// without counting a write, and its history in the stack is discarded.
+func (LIRSWSRObject *LIRSWSR) Trim(block int) {
+	LIRSWSRObject.writeBack.Discard(block)
+	delete(LIRSWSRObject.LIR, block)
+	delete(LIRSWSRObject.HIR, block)
+	LIRSWSRObject.orderedList.Delete(block)
+	LIRSWSRObject.orderedStack.Delete(block)
+	LIRSWSRObject.stackPruning()
+}
+
+func (LIRSWSRObject *LIRSWSR) handleLIRBlock(block int, op simulator.Op) (err error) {
 	LIRSWSRObject.hit += 1
+	LIRSWSRObject.Notify(simulator.EventHitLIR, block)
 	key, _, ok := LIRSWSRObject.orderedStack.GetFirst()
 	if !ok {
 		return errors.New("orderedStack is empty")
@@ -100,100 +141,82 @@ func (LIRSWSRObject *LIRSWSR) handleLIRBlock(block int, op string) (err error) {
 		// check stack
 		LIRSWSRObject.condition1(false)
 	}
-	LIRSWSRObject.addToStack(block, op)
-	LIRSWSRObject.orderedStack.Set(block, &BlockInfo{
-		ColdFlag: true, // reset as cold
-		access:   0,    // re-Initialize access count
-	})
+	LIRSWSRObject.addToStack(block)
+	// a hit counts towards clearing the cold flag rather than setting it
+	// again, or a hot dirty page would never earn its second chance
 	LIRSWSRObject.incrementAccess(block)
 	return nil
 }
 
-func (LIRSWSRObject *LIRSWSR) handleHIRResidentBlock(block int, op string) {
+func (LIRSWSRObject *LIRSWSR) handleHIRResidentBlock(block int, op simulator.Op) {
 	LIRSWSRObject.hit += 1
-	if _, ok := LIRSWSRObject.orderedStack.Get(block); ok { //if x block is in stack, move to LIR
-
-		LIRSWSRObject.makeLIR(block) // change x block to LIR with makeLIR
-		// LIRSWSRObject.removeFromList(block) //delete the x block from list q
+	LIRSWSRObject.Notify(simulator.EventHitResidentHIR, block)
+	_, inStack := LIRSWSRObject.orderedStack.Get(block)
+	// requested x block added to top of the stack before pruning, so it
+	// cannot stop the pruning where it was
+	LIRSWSRObject.addToStack(block)
+	if inStack { //if x block is in stack, move to LIR
+		LIRSWSRObject.makeLIR(block)   // change x block to LIR with makeLIR, which deletes it from list q
 		LIRSWSRObject.condition1(true) //check condition 1 with value true (because HIRresident)
 	} else {
 		// condition2: block is not in stack, move to end of list
 		LIRSWSRObject.orderedList.MoveLast(block)
-		LIRSWSRObject.condition1(true)
-
 	}
-	LIRSWSRObject.addToStack(block, op) // requested x block added to top of the stack
 	LIRSWSRObject.incrementAccess(block)
 }
 
-func (LIRSWSRObject *LIRSWSR) handleHIRNonResidentBlock(block int, op string) {
+func (LIRSWSRObject *LIRSWSR) handleHIRNonResidentBlock(block int, op simulator.Op) {
 	LIRSWSRObject.miss += 1
-	LIRSWSRObject.addToList(block, op)                      //insert the x block to the list
-	if _, ok := LIRSWSRObject.orderedStack.Get(block); ok { // block is in stack, move to LIR
-
-		// LIRSWSRObject.makeLIR(block)        // change x block to LIR with makeLIR
-		// LIRSWSRObject.removeFromList(block) //delete the x block from list q
-		LIRSWSRObject.condition3(true) //check condition 2 with value true (because HIR non resident)
-
+	LIRSWSRObject.Notify(simulator.EventMiss, block)
+	LIRSWSRObject.addToList(block) //insert the x block to the list
+	_, inStack := LIRSWSRObject.orderedStack.Get(block)
+	LIRSWSRObject.addToStack(block) // the requested x block the top of the stack
+	if inStack {                    // block is in stack, move to LIR
+		LIRSWSRObject.makeLIR(block)   // change x block to LIR with makeLIR, which deletes it from list q
+		LIRSWSRObject.condition1(true) //check condition 3 with value true (because HIR non resident)
 	} else {
 		LIRSWSRObject.makeHIR(block)
-
 	}
-	LIRSWSRObject.addToStack(block, op) // the requested x block the top of the stack
-	LIRSWSRObject.orderedStack.Set(block, &BlockInfo{
-		ColdFlag: true, // reset as cold
-		//	access:   0,    // re-Initialize access count
-	})
+	// the block is read back from flash, so the hits it had in the stack no
+	// longer count towards clearing its cold flag
+	if blockInfo, ok := LIRSWSRObject.orderedStack.Get(block); ok {
+		blockInfo.(*BlockInfo).access = 0
+	}
 	LIRSWSRObject.incrementAccess(block)
 }
 
-func (LIRSWSRObject *LIRSWSR) addToList(block int, op string) {
-	if LIRSWSRObject.orderedList.Len() == LIRSWSRObject.HIRSize {
-		LIRSWSRObject.orderedList.PopFirst()
+func (LIRSWSRObject *LIRSWSR) countHit(op simulator.Op) {
+	if op == simulator.OpWrite {
+		LIRSWSRObject.writeHit++
+	} else {
+		LIRSWSRObject.readHit++
 	}
+}
 
-	if op == "W" {
-		//LIRSWSRObject.writeCount++ // Increment the write count
-		LIRSWSRObject.orderedList.Set(block, &BlockInfo{
-			Address:   block,
-			Operation: op,
-			DirtyPage: true, // Set as dirty page
-			ColdFlag:  true, // Set as cold
-			access:    0,    // Initialize access count
-		})
-	} else {
-		LIRSWSRObject.orderedList.Set(block, &BlockInfo{
-			Address:   block,
-			Operation: op,
-			DirtyPage: false, // Set as clean page
-			ColdFlag:  true,  // Set as cold
-			access:    0,     // Initialize access count
-		})
+func (LIRSWSRObject *LIRSWSR) countMiss(op simulator.Op) {
+	if op == simulator.OpRead {
+		LIRSWSRObject.readMiss++
+	}
+}
+
+func (LIRSWSRObject *LIRSWSR) addToList(block int) {
+	if LIRSWSRObject.orderedList.Len() == LIRSWSRObject.HIRSize {
+		if key, _, ok := LIRSWSRObject.orderedList.PopFirst(); ok {
+			LIRSWSRObject.writeBack.Evict(key.(int))
+		}
 	}
+	LIRSWSRObject.orderedList.Set(block, 1)
 }
 
-func (LIRSWSRObject *LIRSWSR) addToStack(block int, op string) {
+func (LIRSWSRObject *LIRSWSR) addToStack(block int) {
 	if _, ok := LIRSWSRObject.orderedStack.Get(block); ok {
 		LIRSWSRObject.orderedStack.MoveLast(block)
 		return
-
-	}
-	// Check if the block is introduced for write request for the first time or if it is a dirty page
-	blockInfo := &BlockInfo{
-		Address:   block,
-		Operation: op,
-		ColdFlag:  true, // Set as cold
-		access:    0,    // Initialize access count
-	}
-
-	if op == "W" {
-		blockInfo.DirtyPage = true // Set DirtyPage to true for write operation
-		//LIRSWSRObject.writeCount++ // Increment the write count
-	} else {
-		blockInfo.DirtyPage = false // Set DirtyPage to false for other operations
 	}
-
-	LIRSWSRObject.orderedStack.Set(block, blockInfo)
+	LIRSWSRObject.orderedStack.Set(block, &BlockInfo{
+		ColdFlag: true, // Set as cold
+		access:   0,    // Initialize access count
+	})
 }
 
 func (LIRSWSRObject *LIRSWSR) removeFromList(block int) {
@@ -201,83 +224,76 @@ func (LIRSWSRObject *LIRSWSR) removeFromList(block int) {
 }
 
 func (LIRSWSRObject *LIRSWSR) makeLIR(block int) {
+	if _, ok := LIRSWSRObject.LIR[block]; !ok {
+		LIRSWSRObject.Notify(simulator.EventPromotedToLIR, block)
+	}
 	delete(LIRSWSRObject.HIR, block)
+	LIRSWSRObject.removeFromList(block)
 	LIRSWSRObject.LIR[block] = 1
 }
 
 func (LIRSWSRObject *LIRSWSR) makeHIR(block int) {
+	if _, ok := LIRSWSRObject.LIR[block]; ok {
+		LIRSWSRObject.Notify(simulator.EventDemotedToHIR, block)
+	}
 	delete(LIRSWSRObject.LIR, block)
 	LIRSWSRObject.HIR[block] = 1
 }
 
+// condition1 pops the LIR block at the bottom of the stack. With removeLIR
+// it makes room for a block just promoted to LIR: a clean or cold-dirty block
+// is demoted to the end of the list Q, while a dirty block that is not cold
+// gets a second chance at the top of the stack with its cold flag set, and
+// the next bottom block is examined instead. Conditions 1 and 3 of the paper
+// both end here.
 func (LIRSWSRObject *LIRSWSR) condition1(removeLIR bool) (err error) {
-	key, _, ok := LIRSWSRObject.orderedStack.PopFirst()
-	if !ok {
-		return errors.New("orderedStack is empty")
-	}
-
-	if removeLIR {
-		LIRSWSRObject.writeCount++
-		block := key.(int) //check the lir page bottom of the stack
-		if LIRSWSRObject.isBlockColdDirty(block) || LIRSWSRObject.isColdFlag(block) {
-			// Clean page or cold-dirty page moves to the end of the list Q
-			LIRSWSRObject.makeLIR(block)        // change x block to LIR with makeLIR
-			LIRSWSRObject.removeFromList(block) //delete the x block from list q
-			LIRSWSRObject.makeHIR(block)
-			LIRSWSRObject.orderedList.Set(block, 1)
-			LIRSWSRObject.orderedList.MoveLast(block)
-		} else {
-			//Not-cold dirty page in the bottom of the stack S is moved to the top with Cold flag set
-			LIRSWSRObject.miss += 1
-			LIRSWSRObject.orderedStack.Set(block, &BlockInfo{
-				ColdFlag: true, // Set as cold
-				access:   0,    // Initialize access count
-			})
-			LIRSWSRObject.orderedStack.MoveLast(block)
+	for {
+		key, _, ok := LIRSWSRObject.orderedStack.GetFirst()
+		if !ok {
+			return errors.New("orderedStack is empty")
 		}
-	}
-
-	LIRSWSRObject.stackPruning()
-	return nil
-}
-
-func (LIRSWSRObject *LIRSWSR) condition3(removeLIR bool) (err error) {
-	key, _, ok := LIRSWSRObject.orderedStack.PopFirst()
-	if !ok {
-		return errors.New("orderedStack is empty")
-	}
+		block := key.(int) //check the lir page bottom of the stack
+		// the flags of the block go with it, so read them before popping it
+		demote := !LIRSWSRObject.isDirtyPage(block) || LIRSWSRObject.isBlockColdDirty(block)
+		LIRSWSRObject.orderedStack.PopFirst()
 
-	if removeLIR {
-		block := key.(int)
-		if LIRSWSRObject.isBlockColdDirty(block) || LIRSWSRObject.isColdFlag(block) {
+		if !removeLIR {
+			break
+		}
+		if demote {
 			// Clean page or cold-dirty page moves to the end of the list Q
-			LIRSWSRObject.makeLIR(block)        // change x block to LIR with makeLIR
-			LIRSWSRObject.removeFromList(block) //delete the x block from list q
+			LIRSWSRObject.Notify(simulator.EventPrunedFromStack, block)
 			LIRSWSRObject.makeHIR(block)
 			LIRSWSRObject.orderedList.Set(block, 1)
-			LIRSWSRObject.orderedList.MoveLast(block)
-		} else {
-			// Not-cold dirty page in the bottom of the stack S is moved to the top with Cold flag set
-			LIRSWSRObject.miss += 1
-			LIRSWSRObject.orderedStack.Set(block, &BlockInfo{
-				ColdFlag: true, // Set as cold
-				access:   0,    // Initialize access count
-			})
-			LIRSWSRObject.orderedStack.MoveLast(block)
+			break
 		}
+		//Not-cold dirty page in the bottom of the stack S is moved to the top with Cold flag set
+		LIRSWSRObject.orderedStack.Set(block, &BlockInfo{
+			ColdFlag: true, // Set as cold
+			access:   0,    // Initialize access count
+		})
+		LIRSWSRObject.Notify(simulator.EventColdFlagSet, block)
+		LIRSWSRObject.stackPruning()
 	}
 
 	LIRSWSRObject.stackPruning()
 	return nil
 }
 
+// stackPruning pops HIR blocks off the bottom of the stack until an LIR block
+// is there. It peeks at the bottom each time rather than iterating, since an
+// iterator does not survive the removal of the entry it stands on.
 func (LIRSWSRObject *LIRSWSR) stackPruning() {
-	iter := LIRSWSRObject.orderedStack.Iter()
-	for k, _, ok := iter.Next(); ok; k, _, ok = iter.Next() {
+	for {
+		k, _, ok := LIRSWSRObject.orderedStack.GetFirst()
+		if !ok {
+			return
+		}
 		if _, ok := LIRSWSRObject.LIR[k]; ok {
-			break
+			return
 		}
 		LIRSWSRObject.orderedStack.PopFirst()
+		LIRSWSRObject.Notify(simulator.EventPrunedFromStack, k.(int))
 	}
 }
 
@@ -288,16 +304,16 @@ func (LIRSWSRObject *LIRSWSR) isColdFlag(block int) bool {
 	}
 	return false
 }
+
+// isDirtyPage asks the write-back model, which knows whether the cached copy
+// has been written since it was last written back.
 func (LIRSWSRObject *LIRSWSR) isDirtyPage(block int) bool {
-	if blockInfo, ok := LIRSWSRObject.orderedStack.Get(block); ok {
-		return blockInfo.(*BlockInfo).DirtyPage
-	}
-	return false
+	return LIRSWSRObject.writeBack.IsDirty(block)
 }
 
 func (LIRSWSRObject *LIRSWSR) isBlockColdDirty(block int) bool {
 	if blockInfo, ok := LIRSWSRObject.orderedStack.Get(block); ok {
-		return blockInfo.(*BlockInfo).ColdFlag && blockInfo.(*BlockInfo).DirtyPage
+		return LIRSWSRObject.isDirtyPage(block) && (blockInfo.(*BlockInfo).ColdFlag && LIRSWSRObject.isColdFlag(block))
 	}
 	return false
 }
@@ -305,26 +321,60 @@ func (LIRSWSRObject *LIRSWSR) isBlockColdDirty(block int) bool {
 func (LIRSWSRObject *LIRSWSR) incrementAccess(block int) {
 	if blockInfo, ok := LIRSWSRObject.orderedStack.Get(block); ok {
 		blockInfo.(*BlockInfo).access++
+		if blockInfo.(*BlockInfo).access == 2 {
+			// isColdFlag no longer holds for the block
+			LIRSWSRObject.Notify(simulator.EventColdFlagCleared, block)
+		}
+	}
+}
+
+func (LIRSWSRObject *LIRSWSR) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      LIRSWSRObject.cacheSize,
+		Hits:           LIRSWSRObject.hit,
+		Misses:         LIRSWSRObject.miss,
+		ReadHits:       LIRSWSRObject.readHit,
+		ReadMisses:     LIRSWSRObject.readMiss,
+		WriteHits:      LIRSWSRObject.writeHit,
+		DirtyEvictions: LIRSWSRObject.writeBack.DirtyEvictions,
+		CleanEvictions: LIRSWSRObject.writeBack.CleanEvictions,
+		FlashWrites:    LIRSWSRObject.writeBack.FlashWrites,
+		ResidentSize:   len(LIRSWSRObject.LIR) + LIRSWSRObject.orderedList.Len(),
+		StackSize:      LIRSWSRObject.orderedStack.Len(),
+		ListSize:       LIRSWSRObject.orderedList.Len(),
 	}
 }
 
-func (LIRSWSRObject *LIRSWSR) PrintToFile(file *os.File, start time.Time) (err error) {
-	duration := time.Since(start)
-	hitRatio := 100 * float32(float32(LIRSWSRObject.hit)/float32(LIRSWSRObject.hit+LIRSWSRObject.miss))
-	result := fmt.Sprintf(`_______________________________________________________
-LIRSWSRVERSIA
-cache size : %v
-cache hit : %v
-cache miss : %v
-hit ratio : %v
-list size : %v
-stack size : %v
-lir capacity: %v
-hir capacity: %v
-write count : %v
-duration : %v
-!LIRSWSR|%v|%v|%v
-`, LIRSWSRObject.cacheSize, LIRSWSRObject.hit, LIRSWSRObject.miss, hitRatio, LIRSWSRObject.orderedList.Len(), LIRSWSRObject.orderedStack.Len(), LIRSWSRObject.LIRSize, LIRSWSRObject.HIRSize, LIRSWSRObject.writeCount, duration.Seconds(), LIRSWSRObject.cacheSize, LIRSWSRObject.hit, LIRSWSRObject.hit+LIRSWSRObject.miss)
-	_, err = file.WriteString(result)
-	return err
+// CheckInvariants verifies that the stack, the list and the LIR/HIR maps agree
+// with each other and returns the first inconsistency found.
+func (LIRSWSRObject *LIRSWSR) CheckInvariants() error {
+	if len(LIRSWSRObject.LIR) > LIRSWSRObject.LIRSize {
+		return fmt.Errorf("%d LIR blocks exceed the LIR capacity of %d", len(LIRSWSRObject.LIR), LIRSWSRObject.LIRSize)
+	}
+	if LIRSWSRObject.orderedList.Len() > LIRSWSRObject.HIRSize {
+		return fmt.Errorf("%d resident HIR blocks exceed the HIR capacity of %d", LIRSWSRObject.orderedList.Len(), LIRSWSRObject.HIRSize)
+	}
+	if key, _, ok := LIRSWSRObject.orderedStack.GetFirst(); ok {
+		if _, isLIR := LIRSWSRObject.LIR[key]; !isLIR {
+			return fmt.Errorf("block %v at the bottom of the stack is not LIR", key)
+		}
+	}
+	for block := range LIRSWSRObject.LIR {
+		if _, ok := LIRSWSRObject.HIR[block]; ok {
+			return fmt.Errorf("block %v is both LIR and HIR", block)
+		}
+		if _, ok := LIRSWSRObject.orderedStack.Get(block); !ok {
+			return fmt.Errorf("LIR block %v is not in the stack", block)
+		}
+	}
+	iter := LIRSWSRObject.orderedList.Iter()
+	for block, _, ok := iter.Next(); ok; block, _, ok = iter.Next() {
+		if _, isLIR := LIRSWSRObject.LIR[block]; isLIR {
+			return fmt.Errorf("list entry %v is an LIR block", block)
+		}
+		if _, isHIR := LIRSWSRObject.HIR[block]; !isHIR {
+			return fmt.Errorf("list entry %v is not a resident HIR block", block)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,119 @@
+package lirswsr
+
+import (
+	"math/rand"
+	"testing"
+
+	"golang/simulator"
+	"golang/simulator/simtest"
+)
+
+type recorder []simulator.Event
+
+func (r *recorder) Observe(e simulator.Event) {
+	*r = append(*r, e)
+}
+
+func (r recorder) count(kind simulator.EventKind, addr int) int {
+	n := 0
+	for _, e := range r {
+		if e.Kind == kind && e.Addr == addr {
+			n++
+		}
+	}
+	return n
+}
+
+func replay(t *testing.T, l *LIRSWSR, traces []simulator.Trace) {
+	t.Helper()
+	for i, trace := range traces {
+		if err := l.Get(trace); err != nil {
+			t.Fatalf("access %d: %v", i, err)
+		}
+		if err := l.CheckInvariants(); err != nil {
+			t.Fatalf("access %d: %v", i, err)
+		}
+	}
+}
+
+func reads(addrs ...int) []simulator.Trace {
+	traces := make([]simulator.Trace, len(addrs))
+	for i, addr := range addrs {
+		traces[i] = simulator.Trace{Addr: addr}
+	}
+	return traces
+}
+
+func write(addr int) simulator.Trace {
+	return simulator.Trace{Addr: addr, Op: simulator.OpWrite}
+}
+
+// A dirty LIR block hit twice is not cold: at the bottom of the stack it goes
+// back to the top with its cold flag set, and the clean block above it is
+// demoted in its place.
+func TestSecondChanceForHotDirtyBlock(t *testing.T) {
+	l := NewLIRSWSR(4, 25) // 3 LIR blocks, 1 HIR block
+	var events recorder
+	l.SetObserver(&events)
+
+	traces := []simulator.Trace{write(1)}
+	traces = append(traces, reads(2, 3, 1, 1, 2, 3, 4, 5, 4)...)
+	replay(t, l, traces)
+
+	if got := events.count(simulator.EventColdFlagSet, 1); got != 1 {
+		t.Errorf("block 1 got %d second chances, want 1", got)
+	}
+	if _, ok := l.LIR[1]; !ok {
+		t.Error("dirty block 1 was demoted despite its second chance")
+	}
+	if _, ok := l.orderedList.Get(2); !ok {
+		t.Error("clean block 2 is not a resident HIR block")
+	}
+	if stats := l.Stats(); stats.DirtyEvictions != 0 {
+		t.Errorf("%d dirty evictions, want 0", stats.DirtyEvictions)
+	}
+}
+
+// A dirty block that has not been hit since it was loaded, or since its
+// second chance, is cold and demoted like a clean one.
+func TestColdDirtyBlockIsDemoted(t *testing.T) {
+	tests := []struct {
+		name   string
+		traces []simulator.Trace
+	}{
+		{"never hit", append([]simulator.Trace{write(1)}, reads(2, 3, 4, 5, 4)...)},
+		{"after its second chance", append([]simulator.Trace{write(1)}, reads(2, 3, 1, 1, 2, 3, 4, 5, 4, 3, 4, 6, 6)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLIRSWSR(4, 25)
+			replay(t, l, tt.traces)
+
+			if _, ok := l.LIR[1]; ok {
+				t.Error("cold dirty block 1 is still LIR")
+			}
+			if _, ok := l.orderedList.Get(1); !ok {
+				t.Error("cold dirty block 1 is not a resident HIR block")
+			}
+		})
+	}
+}
+
+// Second chances must not let the LIR or HIR sets outgrow their capacity.
+func TestSecondChancesKeepInvariants(t *testing.T) {
+	const cacheSize = 20
+	l := NewLIRSWSR(cacheSize, 10)
+	r := rand.New(rand.NewSource(1))
+	traces := make([]simulator.Trace, 100000)
+	for i := range traces {
+		traces[i] = simulator.Trace{Addr: r.Intn(3 * cacheSize), Op: simulator.Op(r.Intn(2))}
+	}
+	replay(t, l, traces)
+	if stats := l.Stats(); stats.ResidentSize > cacheSize {
+		t.Errorf("%d blocks resident in a cache of %d", stats.ResidentSize, cacheSize)
+	}
+}
+
+func TestHits(t *testing.T) {
+	simtest.RunHitTests(t, func() simtest.Simulator { return NewLIRSWSR(200, 1) }, simtest.LIRSHitTests)
+}
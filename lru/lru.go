@@ -1,10 +1,6 @@
 package lru
 
 import (
-	"fmt"
-	"os"
-	"time"
-
 	"golang/simulator"
 
 	"github.com/secnot/orderedmap"
@@ -13,7 +9,7 @@ import (
 type (
 	Node struct {
 		lba int
-		op  string
+		op  simulator.Op
 	}
 
 	LRU struct {
@@ -21,50 +17,84 @@ type (
 		available int
 		hit       int
 		miss      int
-		wc        int
+		readHit   int
+		readMiss  int
+		writeHit  int
+		writeBack *simulator.WriteBack
 
 		list *orderedmap.OrderedMap
+
+		simulator.Notifier
 	}
 )
 
 func NewLRU(value int) *LRU {
-	return &LRU{
+	lru := &LRU{
 		maxlen:    value,
 		available: value,
 		hit:       0,
 		miss:      0,
-		wc:        0,
 		list:      orderedmap.NewOrderedMap(),
 	}
+	lru.writeBack = simulator.NewWriteBack(&lru.Notifier)
+	return lru
 }
 
 func (lru *LRU) Put(data *Node) (exists bool) {
 
 	if _, ok := lru.list.Get(data.lba); ok {
 		lru.hit++
+		lru.Notify(simulator.EventHit, data.lba)
+		if data.op == simulator.OpWrite {
+			lru.writeHit++
+		} else {
+			lru.readHit++
+		}
 
 		if ok := lru.list.MoveLast(data.lba); !ok {
 			return
 		}
+		lru.writeBack.Access(data.lba, data.op)
 
 		return true
 	} else {
 		lru.miss++
-		lru.wc++
+		if data.op == simulator.OpRead {
+			lru.readMiss++
+		}
+		lru.Notify(simulator.EventMiss, data.lba)
+		if lru.maxlen <= 0 {
+			// nothing can be cached: a write goes straight to flash
+			if data.op == simulator.OpWrite {
+				lru.writeBack.WriteThrough(data.lba)
+			}
+			return false
+		}
 
 		if lru.available > 0 {
 			lru.available--
 		} else {
 			evictedLBA, _, _ := lru.list.GetFirst()
 			lru.list.Delete(evictedLBA)
+			lru.writeBack.Evict(evictedLBA.(int))
 		}
 
 		lru.list.Set(data.lba, data.op)
+		lru.writeBack.Access(data.lba, data.op)
 		return false
 	}
 }
 
 func (lru *LRU) Get(trace simulator.Trace) (err error) {
+	switch trace.Op {
+	case simulator.OpTrim:
+		lru.Trim(trace.Addr)
+		return nil
+	case simulator.OpFlush:
+		lru.writeBack.Flush()
+		return nil
+	}
+
 	obj := new(Node)
 	obj.lba = trace.Addr
 	obj.op = trace.Op
@@ -73,13 +103,27 @@ func (lru *LRU) Get(trace simulator.Trace) (err error) {
 	return nil
 }
 
-func (lru *LRU) PrintToFile(file *os.File, start time.Time) (err error) {
-	file.WriteString(fmt.Sprintf("cache size: %d\n", lru.maxlen))
-	file.WriteString(fmt.Sprintf("cache hit: %d\n", lru.hit))
-	file.WriteString(fmt.Sprintf("cache miss: %d\n", lru.miss))
-	file.WriteString(fmt.Sprintf("cache hit ratio: %.4f%%\n", float64(lru.hit)/float64(lru.hit+lru.miss)*100))
-	file.WriteString(fmt.Sprintf("write count: %d\n", lru.wc))
-	file.WriteString(fmt.Sprintf("time execution: %8.4f\n", time.Since(start).Seconds()))
+// Trim drops lba from the cache without counting an access or a write.
+func (lru *LRU) Trim(lba int) {
+	if _, ok := lru.list.Get(lba); ok {
+		lru.list.Delete(lba)
+		lru.available++
+	}
+	lru.writeBack.Discard(lba)
+}
 
-	return nil
+func (lru *LRU) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      lru.maxlen,
+		Hits:           lru.hit,
+		Misses:         lru.miss,
+		ReadHits:       lru.readHit,
+		ReadMisses:     lru.readMiss,
+		WriteHits:      lru.writeHit,
+		DirtyEvictions: lru.writeBack.DirtyEvictions,
+		CleanEvictions: lru.writeBack.CleanEvictions,
+		FlashWrites:    lru.writeBack.FlashWrites,
+		ResidentSize:   lru.list.Len(),
+		ListSize:       lru.list.Len(),
+	}
 }
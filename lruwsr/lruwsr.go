@@ -0,0 +1,126 @@
+package lruwsr
+
+import (
+	"golang/simulator"
+
+	"github.com/secnot/orderedmap"
+)
+
+type (
+	page struct {
+		cold bool
+	}
+
+	// LRUWSR is LRU with Write Sequence Reordering. A dirty page reaching the
+	// LRU end is not evicted straight away: it gets a cold flag and is moved
+	// back to the MRU end. It is only written back if it reaches the LRU end
+	// again still cold, i.e. without being referenced in between.
+	LRUWSR struct {
+		cacheSize int
+		hit       int
+		miss      int
+		readHit   int
+		readMiss  int
+		writeHit  int
+		writeBack *simulator.WriteBack
+
+		// list holds page address -> *page, least recently used first
+		list *orderedmap.OrderedMap
+
+		simulator.Notifier
+	}
+)
+
+func NewLRUWSR(cacheSize int) *LRUWSR {
+	l := &LRUWSR{
+		cacheSize: cacheSize,
+		list:      orderedmap.NewOrderedMap(),
+	}
+	l.writeBack = simulator.NewWriteBack(&l.Notifier)
+	return l
+}
+
+func (l *LRUWSR) Get(trace simulator.Trace) (err error) {
+	block := trace.Addr
+	write := trace.Op == simulator.OpWrite
+
+	switch trace.Op {
+	case simulator.OpTrim:
+		l.list.Delete(block)
+		l.writeBack.Discard(block)
+		return nil
+	case simulator.OpFlush:
+		l.writeBack.Flush()
+		return nil
+	}
+
+	if value, ok := l.list.Get(block); ok {
+		l.hit++
+		l.Notify(simulator.EventHit, block)
+		if write {
+			l.writeHit++
+		} else {
+			l.readHit++
+		}
+		l.writeBack.Access(block, trace.Op)
+		p := value.(*page)
+		if p.cold {
+			p.cold = false
+			l.Notify(simulator.EventColdFlagCleared, block)
+		}
+		l.list.MoveLast(block)
+		return nil
+	}
+
+	l.miss++
+	if !write {
+		l.readMiss++
+	}
+	l.Notify(simulator.EventMiss, block)
+	if l.cacheSize <= 0 {
+		if write {
+			l.writeBack.WriteThrough(block)
+		}
+		return nil
+	}
+	if l.list.Len() >= l.cacheSize {
+		l.evict()
+	}
+	l.list.Set(block, &page{})
+	l.writeBack.Access(block, trace.Op)
+	return nil
+}
+
+func (l *LRUWSR) evict() {
+	for {
+		key, value, _ := l.list.GetFirst()
+		p := value.(*page)
+		if l.writeBack.IsDirty(key.(int)) && !p.cold {
+			// second chance for a hot dirty page
+			p.cold = true
+			l.list.MoveLast(key)
+			l.Notify(simulator.EventColdFlagSet, key.(int))
+			continue
+		}
+
+		l.list.Delete(key)
+		l.writeBack.Evict(key.(int))
+		return
+	}
+}
+
+func (l *LRUWSR) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      l.cacheSize,
+		Hits:           l.hit,
+		Misses:         l.miss,
+		ReadHits:       l.readHit,
+		ReadMisses:     l.readMiss,
+		WriteHits:      l.writeHit,
+		DirtyEvictions: l.writeBack.DirtyEvictions,
+		CleanEvictions: l.writeBack.CleanEvictions,
+		FlashWrites:    l.writeBack.FlashWrites,
+		ResidentSize:   l.list.Len(),
+		ListSize:       l.list.Len(),
+	}
+}
@@ -1,12 +1,22 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"golang/arc"
+	"golang/cflru"
+	"golang/clock"
+	"golang/clockpro"
+	"golang/energy"
 	"golang/lirs"
 	"golang/lirswsr"
 	"golang/lru"
+	"golang/lruwsr"
+	"golang/opt"
+	"golang/report"
 	"golang/simulator"
+	"golang/tracefile"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -14,79 +24,296 @@ import (
 	"time"
 )
 
+// parameters shared by every configuration built by newSimulator and run by
+// sweep or fanOut
+var (
+	cflruWindow = flag.Int("cflru-window", 50, "clean-first window of CFLRU, in percent of the cache size")
+	arcSample   = flag.Int("arc-sample", 1000, "record the ARC target size every this many accesses (0 disables)")
+	flushAtEnd  = flag.Bool("flush", false, "write back the dirty pages left in the cache at the end of the trace")
+	paranoid    = flag.Bool("paranoid", false, "check the internal invariants of LIRS and LIRSWSR after every access and stop at the first violation")
+
+	energyEnabled = flag.Bool("energy", false, "estimate the energy of every run from its operation counts")
+	energyDRAM    = flag.Float64("energy-dram", 0.5, "energy of a DRAM page access, in microjoules")
+	energyRead    = flag.Float64("energy-read", 4.72, "energy of a flash page read, in microjoules")
+	energyProgram = flag.Float64("energy-program", 38.04, "energy of a flash page program, in microjoules")
+	energyErase   = flag.Float64("energy-erase", 527.68, "energy of a flash block erase, in microjoules")
+)
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "mrc":
+			runMRC(os.Args[2:])
+			return
+		case "analyze":
+			runAnalyze(os.Args[2:])
+			return
+		case "generate":
+			runGenerate(os.Args[2:])
+			return
+		}
+	}
+
 	var (
-		traces    []simulator.Trace = make([]simulator.Trace, 0)
-		simulator simulator.Simulator
-		timeStart time.Time
-		out       *os.File
-		fs        os.FileInfo
-		filePath  string
-		outPath   string
-		algorithm string
-		err       error
-		cacheList []int
+		traces     []simulator.Trace = make([]simulator.Trace, 0)
+		out        *os.File
+		fs         os.FileInfo
+		formatter  report.Formatter
+		results    []report.Result
+		filePath   string
+		outPath    string
+		algorithms []string
+		err        error
+		cacheList  []int
+		sampler    *tracefile.Sampler
 		//cachepath    string
 	)
 
-	if len(os.Args) < 4 {
-		fmt.Println("program <algorithm[LRU/LIRS/LIRSWSR]> [file] [trace size]...")
+	reportFormat := flag.String("report", "text", "result format: text, json or csv; text now uses the LIRS layout for every policy, without the lir and hir capacity lines")
+	workers := flag.Int("workers", 1, "number of configurations simulated concurrently")
+	traceFormat := flag.String("format", tracefile.FormatAddr, "trace format: addr, spc, umass or msr")
+	pageSize := flag.Int("page-size", tracefile.PageSize, "page size in bytes used to split sized block requests into page accesses")
+	stream := flag.Bool("stream", false, "read the trace once and feed every configuration from that single pass instead of loading it into memory (ignores -workers)")
+	sampleRate := flag.Float64("sample", 1, "simulate only this fraction of the block addresses (SHARDS spatial sampling) with cache sizes scaled by the same fraction")
+	sampleCheck := flag.Bool("sample-check", false, "also simulate the full trace and report the error of the sampled hit ratios")
+	flag.Usage = func() {
+		fmt.Println("program [flags] <algorithm[LRU/LIRS/LIRSWSR/LRUWSR/CFLRU/ARC/CLOCK/CLOCKPRO/OPT/WAOPT],...> [file] [trace size]...")
+		fmt.Println("program mrc [flags] <file> [cache size]...")
+		fmt.Println("program analyze [flags] <file>")
+		fmt.Println("program generate [flags] <workload>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 3 || *workers < 1 || *pageSize < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	formatter, err = report.NewFormatter(*reportFormat)
+	if err != nil {
+		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	algorithm = os.Args[1]
+	algorithms, err = validateAlgorithms(flag.Arg(0))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
 
-	filePath = os.Args[2]
+	filePath = flag.Arg(1)
 	if fs, err = os.Stat(filePath); os.IsNotExist(err) {
 		fmt.Printf("%v does not exists\n", filePath)
 		os.Exit(1)
 	}
 
-	cacheList, err = validateTraceSize(os.Args[3:])
+	cacheList, err = validateTraceSize(flag.Args()[2:])
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	traces, err = readFile(filePath)
-	if err != nil {
-		log.Fatalf("error reading file: %v", err)
+	if *sampleRate != 1 {
+		if sampler, err = tracefile.NewSampler(*sampleRate); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *ftlEnabled && *ftlCapacity == 0 && *stream {
+		fmt.Println("-ftl with -stream needs -ftl-capacity")
+		os.Exit(1)
+	}
+	if *wearEnabled {
+		if *wearCapacity == 0 && *stream {
+			fmt.Println("-wear with -stream needs -wear-capacity")
+			os.Exit(1)
+		}
+		if err = wearModel(1).Validate(); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *ftlEnabled {
+		// check the geometry before simulating anything
+		if _, err = newFTL(1); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *energyEnabled {
+		if err = energyModel().Validate(); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *latencyEnabled {
+		if _, err = newLatencyModel(); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if *sampleCheck && (sampler == nil || *stream) {
+		fmt.Println("-sample-check needs -sample and the trace in memory (no -stream)")
+		os.Exit(1)
+	}
+	jobs := newJobs(algorithms, cacheList, sampler)
+	for _, j := range jobs {
+		if size := j.simulatedSize(); size < minCacheSize(j.algorithm) {
+			fmt.Printf("%v needs a cache of at least %d blocks, not %d", j.algorithm, minCacheSize(j.algorithm), size)
+			if j.sampler != nil {
+				fmt.Printf(" (%d scaled by the sample rate)", j.cacheSize)
+			}
+			fmt.Println()
+			os.Exit(1)
+		}
 	}
 
-	outPath = fmt.Sprintf("%v_%v_%v.txt", time.Now().Unix(), algorithm, fs.Name())
-
-	out, err = os.Create(outPath)
-	if err != nil {
-		log.Fatal(err.Error())
+	if *eventsPath != "" {
+		eventsFile, err := os.Create(*eventsPath)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer eventsFile.Close()
+		events = newEventLog(eventsFile)
 	}
-	defer out.Close()
 
-	for _, cache := range cacheList {
-		switch strings.ToLower(algorithm) {
-		case "lirs":
-			simulator = lirs.NewLIRS(cache, 1)
-		case "lru":
-			simulator = lru.NewLRU(cache)
-		case "lirswsr":
-			simulator = lirswsr.NewLIRSWSR(cache, 1)
-		default:
-			log.Fatal("algorithm not supported")
+	if *stream {
+		for _, algorithm := range algorithms {
+			if offline(algorithm) {
+				fmt.Printf("%v needs the whole trace in memory and cannot be used with -stream\n", algorithm)
+				os.Exit(1)
+			}
+		}
+		results, err = streamFile(jobs, filePath, *traceFormat, *pageSize, sampler, fs.Name())
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	} else {
+		traces, err = readFile(filePath, *traceFormat, *pageSize)
+		if err != nil {
+			log.Fatalf("error reading file: %v", err)
 		}
 
-		timeStart = time.Now()
+		sampled := traces
+		if sampler != nil {
+			sampled = sampler.Filter(traces)
+		}
+		results, err = sweep(jobs, sampled, fs.Name(), *workers)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
 
-		for _, trace := range traces {
-			err = simulator.Get(trace)
+		if *sampleCheck {
+			full, err := sweep(newJobs(algorithms, cacheList, nil), traces, fs.Name(), *workers)
 			if err != nil {
 				log.Fatal(err.Error())
 			}
+			if err = report.SampleError(os.Stdout, full, results); err != nil {
+				log.Fatal(err.Error())
+			}
 		}
+	}
 
-		simulator.PrintToFile(out, timeStart)
+	if events != nil {
+		if err = events.Flush(); err != nil {
+			log.Fatalf("error writing events: %v", err)
+		}
+	}
+
+	outPath = fmt.Sprintf("%v_%v_%v.%v", time.Now().Unix(), strings.Join(algorithms, "-"), fs.Name(), formatter.Extension())
+
+	out, err = os.Create(outPath)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer out.Close()
+
+	if err = formatter.Format(out, results); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if len(algorithms) > 1 {
+		if err = report.Compare(os.Stdout, results); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	if *wearEnabled {
+		if err = report.Lifetime(os.Stdout, results); err != nil {
+			log.Fatal(err.Error())
+		}
 	}
 	fmt.Println("Done")
 }
 
+func validateAlgorithms(list string) (algorithms []string, err error) {
+	for _, algorithm := range strings.Split(list, ",") {
+		algorithm = strings.ToUpper(strings.TrimSpace(algorithm))
+		if _, err = newSimulator(algorithm, 0, nil); err != nil {
+			return algorithms, err
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
+}
+
+// newSimulator builds the policy named algorithm. traces is only used by the
+// offline policies, which pre-scan the trace they are about to be fed.
+func newSimulator(algorithm string, cache int, traces []simulator.Trace) (simulator.Simulator, error) {
+	switch strings.ToLower(algorithm) {
+	case "opt":
+		return opt.NewOPT(cache, traces), nil
+	case "waopt":
+		return opt.NewWriteAwareOPT(cache, traces, *flushAtEnd), nil
+	case "lirs":
+		return lirs.NewLIRS(cache, 1), nil
+	case "lru":
+		return lru.NewLRU(cache), nil
+	case "lirswsr":
+		return lirswsr.NewLIRSWSR(cache, 1), nil
+	case "lruwsr":
+		return lruwsr.NewLRUWSR(cache), nil
+	case "arc":
+		return arc.NewARC(cache, *arcSample), nil
+	case "clock":
+		return clock.NewCLOCK(cache), nil
+	case "clockpro":
+		return clockpro.NewCLOCKPro(cache), nil
+	case "cflru":
+		return cflru.NewCFLRU(cache, *cflruWindow), nil
+	}
+	return nil, fmt.Errorf("algorithm %v not supported", algorithm)
+}
+
+// energyModel erases blocks of the same size as the FTL would.
+func energyModel() energy.Config {
+	return energy.Config{
+		DRAM:          *energyDRAM,
+		Read:          *energyRead,
+		Program:       *energyProgram,
+		Erase:         *energyErase,
+		PagesPerBlock: *ftlPagesPerBlock,
+	}
+}
+
+func offline(algorithm string) bool {
+	switch strings.ToLower(algorithm) {
+	case "opt", "waopt":
+		return true
+	}
+	return false
+}
+
+// minCacheSize is the smallest cache algorithm can simulate: the LIRS
+// policies need a block for each of their LIR and HIR sets.
+func minCacheSize(algorithm string) int {
+	switch strings.ToLower(algorithm) {
+	case "lirs", "lirswsr":
+		return 2
+	}
+	return 0
+}
+
 func validateTraceSize(tracesize []string) (sizeList []int, err error) {
 	var (
 		cacheList []int
@@ -103,34 +330,46 @@ func validateTraceSize(tracesize []string) (sizeList []int, err error) {
 	return cacheList, nil
 }
 
-func readFile(filePath string) (traces []simulator.Trace, err error) {
-	var (
-		file    *os.File
-		scanner *bufio.Scanner
-		row     []string
-		address int
-	)
-	file, err = os.Open(filePath)
+func readFile(filePath string, format string, pageSize int) (traces []simulator.Trace, err error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return traces, err
 	}
 	defer file.Close()
 
-	scanner = bufio.NewScanner(file)
+	reader, err := tracefile.NewFormatReader(file, format)
+	if err != nil {
+		return traces, err
+	}
+	return tracefile.ReadAll(tracefile.NewPageReader(reader, pageSize))
+}
+
+// streamPages passes every page access of the trace at filePath to visit
+// without keeping the trace in memory. When sampler is set only the accesses
+// it keeps are passed.
+func streamPages(filePath string, format string, pageSize int, sampler *tracefile.Sampler, visit func(simulator.Trace)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-	for scanner.Scan() {
-		row = strings.Split(scanner.Text(), ",")
-		address, err = strconv.Atoi(row[0])
+	reader, err := tracefile.NewFormatReader(file, format)
+	if err != nil {
+		return err
+	}
+	var pages simulator.TraceReader = tracefile.NewPageReader(reader, pageSize)
+	if sampler != nil {
+		pages = tracefile.NewSampleReader(pages, sampler)
+	}
+	for {
+		trace, err := pages.Next()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return traces, err
+			return err
 		}
-		traces = append(traces,
-			simulator.Trace{
-				Addr: address,
-				Op:   row[1],
-			},
-		)
+		visit(trace)
 	}
-
-	return traces, nil
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golang/mrc"
+	"golang/tracefile"
+	"log"
+	"os"
+	"time"
+)
+
+// runMRC implements the mrc subcommand: the LRU miss ratio curve of a trace
+// for every cache size, computed in one pass and written as CSV.
+func runMRC(args []string) {
+	flags := flag.NewFlagSet("mrc", flag.ExitOnError)
+	traceFormat := flags.String("format", tracefile.FormatAddr, "trace format: addr, spc, umass or msr")
+	pageSize := flags.Int("page-size", tracefile.PageSize, "page size in bytes used to split sized block requests into page accesses")
+	step := flags.Int("step", 1, "cache size increment between two points of the curve")
+	maxSize := flags.Int("max", 0, "largest cache size of the curve (0 for the largest stack distance of the trace)")
+	sampleRate := flags.Float64("sample", 1, "analyze only this fraction of the block addresses (SHARDS spatial sampling)")
+	flags.Usage = func() {
+		fmt.Println("program mrc [flags] <file> [cache size]...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || *step < 1 || *maxSize < 0 || *pageSize < 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	filePath := flags.Arg(0)
+	fs, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		fmt.Printf("%v does not exists\n", filePath)
+		os.Exit(1)
+	}
+
+	sizes, err := validateTraceSize(flags.Args()[1:])
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	sampler, err := tracefile.NewSampler(*sampleRate)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	analyzer, err := analyzeFile(filePath, *traceFormat, *pageSize, sampler)
+	if err != nil {
+		log.Fatalf("error reading file: %v", err)
+	}
+
+	if len(sizes) == 0 {
+		last := *maxSize
+		if last == 0 {
+			last = int(float64(analyzer.MaxDistance()) / sampler.Rate())
+		}
+		for size := *step; size < last+*step; size += *step {
+			sizes = append(sizes, size)
+		}
+	}
+
+	outPath := fmt.Sprintf("%v_MRC_%v.csv", time.Now().Unix(), fs.Name())
+	out, err := os.Create(outPath)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer out.Close()
+
+	// the sampled trace is evaluated at scaled sizes and reported under the
+	// requested ones
+	scaled := make([]int, len(sizes))
+	for i, size := range sizes {
+		scaled[i] = sampler.Scale(size)
+	}
+	points := analyzer.Curve(scaled)
+	for i := range points {
+		points[i].CacheSize = sizes[i]
+	}
+
+	if err = mrc.WriteCSV(out, points); err != nil {
+		log.Fatal(err.Error())
+	}
+	fmt.Println("Done")
+}
+
+func analyzeFile(filePath string, format string, pageSize int, sampler *tracefile.Sampler) (*mrc.Analyzer, error) {
+	analyzer := mrc.NewAnalyzer()
+	if err := streamPages(filePath, format, pageSize, sampler, analyzer.Access); err != nil {
+		return nil, err
+	}
+	return analyzer, nil
+}
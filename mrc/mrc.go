@@ -0,0 +1,183 @@
+package mrc
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"golang/simulator"
+)
+
+// Analyzer computes the miss ratio curve of LRU in a single pass using
+// Mattson's stack distance: an access hits in an LRU cache of size c iff at
+// most c distinct blocks, itself included, were referenced since the previous
+// access to the same block.
+//
+// Every block is marked in a Fenwick tree at the time of its latest access, so
+// the stack distance is the number of marks from that time to now.
+type Analyzer struct {
+	last       map[int]int
+	tree       []int
+	now        int
+	accesses   int
+	coldMisses int
+	distances  []int
+}
+
+// Point is the outcome of the trace for one cache size.
+type Point struct {
+	CacheSize int
+	Hits      int
+	Misses    int
+}
+
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{
+		last: make(map[int]int),
+		tree: make([]int, 1025),
+	}
+}
+
+// Access records one trace record. Trims forget the block like LRU does;
+// flushes do not change the recency order and are ignored. Forgetting a block
+// brings every older block one place closer, while LRU does not get back the
+// block it evicted before the trim freed a frame, so with trims the curve is
+// slightly optimistic.
+func (a *Analyzer) Access(trace simulator.Trace) {
+	switch trace.Op {
+	case simulator.OpTrim:
+		if t, ok := a.last[trace.Addr]; ok {
+			a.add(t, -1)
+			delete(a.last, trace.Addr)
+		}
+		return
+	case simulator.OpFlush:
+		return
+	}
+
+	a.accesses++
+	if t, ok := a.last[trace.Addr]; ok {
+		distance := a.sum(a.now) - a.sum(t-1)
+		for len(a.distances) < distance {
+			a.distances = append(a.distances, 0)
+		}
+		a.distances[distance-1]++
+		a.add(t, -1)
+		delete(a.last, trace.Addr)
+	} else {
+		a.coldMisses++
+	}
+
+	if a.now == len(a.tree)-1 {
+		a.compact()
+	}
+	a.now++
+	a.last[trace.Addr] = a.now
+	a.add(a.now, 1)
+}
+
+// MaxDistance returns the largest stack distance seen. Caches at least this
+// large only miss on the first access to each block.
+func (a *Analyzer) MaxDistance() int {
+	return len(a.distances)
+}
+
+// Distances returns the number of accesses at every stack distance: index
+// d-1 holds the accesses with distance d.
+func (a *Analyzer) Distances() []int {
+	return append([]int(nil), a.distances...)
+}
+
+// ColdMisses returns the number of first accesses to a block, which miss at
+// any cache size.
+func (a *Analyzer) ColdMisses() int {
+	return a.coldMisses
+}
+
+// Curve returns the hits and misses of an LRU cache of every size in sizes.
+func (a *Analyzer) Curve(sizes []int) []Point {
+	// hits[c] is the number of accesses with a stack distance of at most c
+	hits := make([]int, len(a.distances)+1)
+	for d, count := range a.distances {
+		hits[d+1] = hits[d] + count
+	}
+
+	points := make([]Point, 0, len(sizes))
+	for _, size := range sizes {
+		h := 0
+		if size >= len(hits) {
+			h = hits[len(hits)-1]
+		} else if size > 0 {
+			h = hits[size]
+		}
+		points = append(points, Point{CacheSize: size, Hits: h, Misses: a.accesses - h})
+	}
+	return points
+}
+
+// HitRatio returns the hit ratio as a fraction between 0 and 1.
+func (p Point) HitRatio() float64 {
+	if p.Hits+p.Misses == 0 {
+		return 0
+	}
+	return float64(p.Hits) / float64(p.Hits+p.Misses)
+}
+
+// WriteCSV writes one line per point.
+func WriteCSV(w io.Writer, points []Point) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"cache_size", "hits", "misses", "hit_ratio", "miss_ratio"}); err != nil {
+		return err
+	}
+	for _, p := range points {
+		err := writer.Write([]string{
+			strconv.Itoa(p.CacheSize),
+			strconv.Itoa(p.Hits),
+			strconv.Itoa(p.Misses),
+			strconv.FormatFloat(p.HitRatio(), 'f', 6, 64),
+			strconv.FormatFloat(1-p.HitRatio(), 'f', 6, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// compact renumbers the latest access times to 1..n so the tree only needs to
+// be as large as the footprint rather than the whole trace.
+func (a *Analyzer) compact() {
+	blocks := make([]int, 0, len(a.last))
+	for block := range a.last {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return a.last[blocks[i]] < a.last[blocks[j]]
+	})
+
+	size := 2 * (len(blocks) + 1)
+	if size < 1024 {
+		size = 1024
+	}
+	a.tree = make([]int, size+1)
+	for i, block := range blocks {
+		a.last[block] = i + 1
+		a.add(i+1, 1)
+	}
+	a.now = len(blocks)
+}
+
+func (a *Analyzer) add(i, delta int) {
+	for ; i < len(a.tree); i += i & -i {
+		a.tree[i] += delta
+	}
+}
+
+func (a *Analyzer) sum(i int) (total int) {
+	for ; i > 0; i -= i & -i {
+		total += a.tree[i]
+	}
+	return total
+}
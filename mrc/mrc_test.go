@@ -0,0 +1,86 @@
+package mrc
+
+import (
+	"math/rand"
+	"testing"
+
+	"golang/lru"
+	"golang/simulator"
+)
+
+func replay(t *testing.T, traces []simulator.Trace, size int) simulator.Stats {
+	t.Helper()
+	sim := lru.NewLRU(size)
+	for _, trace := range traces {
+		if err := sim.Get(trace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sim.Stats()
+}
+
+func randomTraces(ops int) []simulator.Trace {
+	r := rand.New(rand.NewSource(1))
+	traces := make([]simulator.Trace, 20000)
+	for i := range traces {
+		traces[i] = simulator.Trace{Addr: r.Intn(500), Op: simulator.Op(r.Intn(ops))}
+	}
+	return traces
+}
+
+var sizes = []int{0, 1, 2, 10, 100, 499, 500, 1000}
+
+// The curve matches an LRU simulated at every size, across enough accesses
+// to compact the tree several times.
+func TestCurveMatchesLRU(t *testing.T) {
+	traces := randomTraces(2)
+	a := NewAnalyzer()
+	for _, trace := range traces {
+		a.Access(trace)
+	}
+	for _, p := range a.Curve(sizes) {
+		stats := replay(t, traces, p.CacheSize)
+		if p.Hits != stats.Hits || p.Misses != stats.Misses {
+			t.Errorf("size %d: curve has %d hits and %d misses, LRU %d and %d", p.CacheSize, p.Hits, p.Misses, stats.Hits, stats.Misses)
+		}
+	}
+}
+
+// With trims the curve may only overestimate the hits of LRU, and only a
+// little.
+func TestCurveWithTrims(t *testing.T) {
+	traces := randomTraces(3)
+	a := NewAnalyzer()
+	for _, trace := range traces {
+		a.Access(trace)
+	}
+	for _, p := range a.Curve(sizes) {
+		stats := replay(t, traces, p.CacheSize)
+		if p.Hits+p.Misses != stats.Accesses() {
+			t.Errorf("size %d: curve has %d accesses, LRU %d", p.CacheSize, p.Hits+p.Misses, stats.Accesses())
+		}
+		if p.Hits < stats.Hits || p.Hits > stats.Hits+stats.Accesses()/100 {
+			t.Errorf("size %d: curve has %d hits, LRU %d", p.CacheSize, p.Hits, stats.Hits)
+		}
+	}
+}
+
+func TestDistances(t *testing.T) {
+	a := NewAnalyzer()
+	for _, addr := range []int{1, 2, 3, 1, 1, 3} {
+		a.Access(simulator.Trace{Addr: addr})
+	}
+	want := []int{1, 1, 1}
+	got := a.Distances()
+	if len(got) != len(want) {
+		t.Fatalf("distances %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("distances %v, want %v", got, want)
+		}
+	}
+	if a.ColdMisses() != 3 || a.MaxDistance() != 3 {
+		t.Errorf("%d cold misses, max distance %d; want 3 and 3", a.ColdMisses(), a.MaxDistance())
+	}
+}
@@ -0,0 +1,265 @@
+package opt
+
+import (
+	"fmt"
+
+	"golang/simulator"
+
+	"github.com/petar/GoLLRB/llrb"
+)
+
+// page orders resident pages by the trace index of their next use, so the
+// maximum of a tree is the page Belady's MIN would evict.
+type page struct {
+	next int
+	addr int
+	// owed is set for a page never used again whose dirty copy would still
+	// be written back by a flush if it stayed cached
+	owed bool
+}
+
+func (p page) Less(than llrb.Item) bool {
+	other := than.(page)
+	if p.next != other.next {
+		return p.next < other.next
+	}
+	if p.owed != other.owed {
+		// of the pages never used again, those owing a write-back go first
+		return other.owed
+	}
+	return p.addr < other.addr
+}
+
+// OPT is Belady's offline optimal policy. It is built from the complete trace
+// and must then be fed that same trace, in order, through Get.
+type OPT struct {
+	cacheSize  int
+	writeAware bool
+	hit        int
+	miss       int
+	readHit    int
+	readMiss   int
+	writeHit   int
+	writeBack  *simulator.WriteBack
+	traces     []simulator.Trace
+	nextUse    []int
+	owed       []bool
+	position   int
+	resident   map[int]page
+	cleanPages *llrb.LLRB
+	dirtyPages *llrb.LLRB
+
+	simulator.Notifier
+}
+
+// NewOPT evicts the resident page whose next use is furthest in the future.
+func NewOPT(cacheSize int, traces []simulator.Trace) *OPT {
+	return newOPT(cacheSize, traces, false)
+}
+
+// NewWriteAwareOPT evicts the clean page whose next use is furthest in the
+// future, and only falls back to a dirty page when every resident page is
+// dirty. It trades hits for fewer dirty evictions. A dirty page that is never
+// used again goes before any clean page still in use when a flush would write
+// it back anyway: a flush in the trace or, with flushAtEnd, the one after it,
+// as long as the page is not trimmed first. Otherwise keeping it saves a write.
+func NewWriteAwareOPT(cacheSize int, traces []simulator.Trace, flushAtEnd bool) *OPT {
+	o := newOPT(cacheSize, traces, true)
+	o.owed = owedWriteBacks(traces, flushAtEnd)
+	return o
+}
+
+func newOPT(cacheSize int, traces []simulator.Trace, writeAware bool) *OPT {
+	o := &OPT{
+		cacheSize:  cacheSize,
+		writeAware: writeAware,
+		traces:     traces,
+		nextUse:    nextUses(traces),
+		resident:   make(map[int]page, cacheSize),
+		cleanPages: llrb.New(),
+		dirtyPages: llrb.New(),
+	}
+	o.writeBack = simulator.NewWriteBack(&o.Notifier)
+	return o
+}
+
+// nextUses returns, for every access, the index of the next access to the
+// same address, or len(traces) if the address is never used again or is
+// trimmed first.
+func nextUses(traces []simulator.Trace) []int {
+	nextUse := make([]int, len(traces))
+	last := make(map[int]int)
+	for i := len(traces) - 1; i >= 0; i-- {
+		trace := traces[i]
+		switch trace.Op {
+		case simulator.OpTrim:
+			delete(last, trace.Addr)
+			continue
+		case simulator.OpFlush:
+			continue
+		}
+		if next, ok := last[trace.Addr]; ok {
+			nextUse[i] = next
+		} else {
+			nextUse[i] = len(traces)
+		}
+		last[trace.Addr] = i
+	}
+	return nextUse
+}
+
+// owedWriteBacks returns, for every access after which its address is never
+// used again, whether a flush comes before any trim of the address, so that a
+// dirty copy left in the cache would be written back. flushAtEnd counts the
+// flush issued once the trace has been replayed.
+func owedWriteBacks(traces []simulator.Trace, flushAtEnd bool) []bool {
+	owed := make([]bool, len(traces))
+	// nextFlush is the index of the next flush, past the end of the trace for
+	// the final one and -1 if there is none
+	nextFlush := -1
+	if flushAtEnd {
+		nextFlush = len(traces)
+	}
+	// nextStop is the index of the next trim or use of each address
+	nextStop := make(map[int]int)
+	for i := len(traces) - 1; i >= 0; i-- {
+		trace := traces[i]
+		if trace.Op == simulator.OpFlush {
+			nextFlush = i
+			continue
+		}
+		if trace.Op != simulator.OpTrim {
+			stop, ok := nextStop[trace.Addr]
+			owed[i] = nextFlush >= 0 && (!ok || nextFlush < stop)
+		}
+		nextStop[trace.Addr] = i
+	}
+	return owed
+}
+
+func (o *OPT) Get(trace simulator.Trace) (err error) {
+	if trace.Op == simulator.OpFlush {
+		// a flush may also be issued after the trace has been replayed
+		if o.position < len(o.traces) && o.traces[o.position] == trace {
+			o.position++
+		}
+		o.flush()
+		return nil
+	}
+
+	if o.position >= len(o.traces) || o.traces[o.position] != trace {
+		return fmt.Errorf("OPT: access %d does not match the pre-scanned trace", o.position)
+	}
+	next, owed := o.nextUse[o.position], o.owed != nil && o.owed[o.position]
+	o.position++
+
+	if trace.Op == simulator.OpTrim {
+		o.remove(trace.Addr)
+		o.writeBack.Discard(trace.Addr)
+		return nil
+	}
+
+	if _, ok := o.resident[trace.Addr]; ok {
+		o.hit++
+		o.Notify(simulator.EventHit, trace.Addr)
+		if trace.Op == simulator.OpWrite {
+			o.writeHit++
+		} else {
+			o.readHit++
+		}
+		o.remove(trace.Addr)
+		o.writeBack.Access(trace.Addr, trace.Op)
+		o.insert(page{next: next, addr: trace.Addr, owed: owed})
+		return nil
+	}
+
+	o.miss++
+	if trace.Op == simulator.OpRead {
+		o.readMiss++
+	}
+	o.Notify(simulator.EventMiss, trace.Addr)
+	if o.cacheSize <= 0 {
+		if trace.Op == simulator.OpWrite {
+			o.writeBack.WriteThrough(trace.Addr)
+		}
+		return nil
+	}
+	if len(o.resident) >= o.cacheSize {
+		o.evict()
+	}
+	o.writeBack.Access(trace.Addr, trace.Op)
+	o.insert(page{next: next, addr: trace.Addr, owed: owed})
+	return nil
+}
+
+// insert adds p to the tree its dirty state in the write-back model calls for.
+func (o *OPT) insert(p page) {
+	o.resident[p.addr] = p
+	if o.writeBack.IsDirty(p.addr) {
+		o.dirtyPages.ReplaceOrInsert(p)
+	} else {
+		o.cleanPages.ReplaceOrInsert(p)
+	}
+}
+
+// remove takes addr out of its tree, leaving the write-back model to the
+// caller.
+func (o *OPT) remove(addr int) {
+	p, ok := o.resident[addr]
+	if !ok {
+		return
+	}
+	if o.writeBack.IsDirty(addr) {
+		o.dirtyPages.Delete(p)
+	} else {
+		o.cleanPages.Delete(p)
+	}
+	delete(o.resident, addr)
+}
+
+func (o *OPT) evict() {
+	victim := o.cleanPages.Max()
+	if dirtyVictim := o.dirtyPages.Max(); dirtyVictim != nil {
+		switch {
+		case victim == nil:
+			victim = dirtyVictim
+		case !o.writeAware || o.owedBack(dirtyVictim.(page)):
+			// a clean page wins a tie: evicting it costs no write
+			if victim.(page).next < dirtyVictim.(page).next {
+				victim = dirtyVictim
+			}
+		}
+	}
+	addr := victim.(page).addr
+	o.remove(addr)
+	o.writeBack.Evict(addr)
+}
+
+// owedBack reports whether the dirty page p is never used again and would be
+// written back by a later flush if it stayed.
+func (o *OPT) owedBack(p page) bool {
+	return p.next == len(o.traces) && p.owed
+}
+
+// flush writes back every dirty page, which moves it to the clean tree.
+func (o *OPT) flush() {
+	for o.dirtyPages.Len() > 0 {
+		o.cleanPages.ReplaceOrInsert(o.dirtyPages.DeleteMin())
+	}
+	o.writeBack.Flush()
+}
+
+func (o *OPT) Stats() simulator.Stats {
+	return simulator.Stats{
+		CacheSize:      o.cacheSize,
+		Hits:           o.hit,
+		Misses:         o.miss,
+		ReadHits:       o.readHit,
+		ReadMisses:     o.readMiss,
+		WriteHits:      o.writeHit,
+		DirtyEvictions: o.writeBack.DirtyEvictions,
+		CleanEvictions: o.writeBack.CleanEvictions,
+		FlashWrites:    o.writeBack.FlashWrites,
+		ResidentSize:   len(o.resident),
+	}
+}
@@ -0,0 +1,114 @@
+package opt
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang/simulator"
+)
+
+// parse reads a trace written as "1,R 2,W 3,T F".
+func parse(t *testing.T, s string) []simulator.Trace {
+	t.Helper()
+	var traces []simulator.Trace
+	for _, field := range strings.Fields(s) {
+		if field == "F" {
+			traces = append(traces, simulator.Trace{Op: simulator.OpFlush})
+			continue
+		}
+		addr, op, _ := strings.Cut(field, ",")
+		a, err := strconv.Atoi(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err := simulator.ParseOp(op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, simulator.Trace{Addr: a, Op: o})
+	}
+	return traces
+}
+
+// run replays traces and, with flushAtEnd, the flush that follows them.
+func run(t *testing.T, o *OPT, traces []simulator.Trace, flushAtEnd bool) simulator.Stats {
+	t.Helper()
+	for i, trace := range traces {
+		if err := o.Get(trace); err != nil {
+			t.Fatalf("access %d: %v", i, err)
+		}
+	}
+	if flushAtEnd {
+		if err := o.Get(simulator.Trace{Op: simulator.OpFlush}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return o.Stats()
+}
+
+// The reference string of the textbook example misses 9 times in 3 frames.
+func TestOPTMisses(t *testing.T) {
+	traces := make([]simulator.Trace, 0, 20)
+	for _, addr := range []int{7, 0, 1, 2, 0, 3, 0, 4, 2, 3, 0, 3, 2, 1, 2, 0, 1, 7, 0, 1} {
+		traces = append(traces, simulator.Trace{Addr: addr})
+	}
+	stats := run(t, NewOPT(3, traces), traces, false)
+	if stats.Misses != 9 || stats.Hits != 11 {
+		t.Errorf("%d misses and %d hits, want 9 and 11", stats.Misses, stats.Hits)
+	}
+}
+
+func TestEvictions(t *testing.T) {
+	tests := []struct {
+		name       string
+		cacheSize  int
+		writeAware bool
+		flushAtEnd bool
+		trace      string
+		dirty      int
+		writes     int
+	}{
+		// pages 1 and 2 are both dead when 3 comes in: the clean one goes
+		{"OPT tie goes to the clean page", 2, false, false, "1,R 2,W 3,R", 0, 0},
+		{"WAOPT tie goes to the clean page", 2, true, false, "1,R 2,W 3,R", 0, 0},
+		{"WAOPT tie with flush", 2, true, true, "1,R 2,W 3,R", 0, 1},
+		{"OPT evicts the dead dirty page", 2, false, false, "1,W 2,R 3,R 2,R", 1, 1},
+		// without a flush, the dead dirty page 1 would never be written
+		{"WAOPT keeps a dead dirty page", 2, true, false, "1,W 2,R 3,R 2,R", 0, 0},
+		{"WAOPT evicts a dead dirty page owed to the final flush", 2, true, true, "1,W 2,R 3,R 2,R", 1, 1},
+		{"WAOPT evicts a dead dirty page owed to a flush in the trace", 2, true, false, "1,W 2,R 3,R 2,R F", 1, 1},
+		{"WAOPT keeps a dead dirty page trimmed before the flush", 2, true, true, "1,W 2,R 3,R 2,R 1,T", 0, 0},
+		{"a read hit keeps a page dirty", 1, false, false, "1,W 1,R 2,R", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traces := parse(t, tt.trace)
+			o := NewOPT(tt.cacheSize, traces)
+			if tt.writeAware {
+				o = NewWriteAwareOPT(tt.cacheSize, traces, tt.flushAtEnd)
+			}
+			stats := run(t, o, traces, tt.flushAtEnd)
+			if stats.DirtyEvictions != tt.dirty || stats.FlashWrites != tt.writes {
+				t.Errorf("%d dirty evictions and %d flash writes, want %d and %d",
+					stats.DirtyEvictions, stats.FlashWrites, tt.dirty, tt.writes)
+			}
+		})
+	}
+}
+
+// Trading hits for writes, WAOPT never misses less than OPT.
+func TestWriteAwareMissesNoLess(t *testing.T) {
+	var traces []simulator.Trace
+	for i := 0; i < 5000; i++ {
+		addr := (i * 7919) % 97
+		traces = append(traces, simulator.Trace{Addr: addr, Op: simulator.Op(addr % 3 % 2)})
+	}
+	for _, flushAtEnd := range []bool{false, true} {
+		opt := run(t, NewOPT(16, traces), traces, flushAtEnd)
+		waopt := run(t, NewWriteAwareOPT(16, traces, flushAtEnd), traces, flushAtEnd)
+		if waopt.Misses < opt.Misses {
+			t.Errorf("flush %v: WAOPT missed %d times, OPT %d", flushAtEnd, waopt.Misses, opt.Misses)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"golang/ftl"
+	"golang/latency"
+	"golang/report"
+	"golang/simulator"
+	"golang/wear"
+	"time"
+)
+
+var (
+	ftlEnabled       = flag.Bool("ftl", false, "feed the pages each cache writes back to a simulated page-mapped SSD and report its write amplification and erases")
+	ftlCapacity      = flag.Int("ftl-capacity", 0, "logical pages of the SSD (0 for the footprint of the trace, which needs the trace in memory)")
+	ftlPagesPerBlock = flag.Int("ftl-pages-per-block", 256, "pages per flash erase block")
+	ftlOverProvision = flag.Float64("ftl-op", 0.07, "over-provisioning of the SSD as a fraction of its logical capacity")
+	ftlGC            = flag.String("ftl-gc", ftl.Greedy, "garbage collection victim selection: greedy or cost-benefit")
+
+	latencyEnabled = flag.Bool("latency", false, "estimate the latency of every access and the total I/O time from a device timing model")
+	latencyHit     = flag.Duration("latency-hit", 100*time.Nanosecond, "DRAM latency paid by every access")
+	latencyRead    = flag.Duration("latency-read", 50*time.Microsecond, "flash page read latency")
+	latencyProgram = flag.Duration("latency-program", 500*time.Microsecond, "flash page program latency")
+	latencyErase   = flag.Duration("latency-erase", 3*time.Millisecond, "flash block erase latency, only charged with -ftl")
+	latencyQD      = flag.Int("latency-qd", 1, "number of requests kept in flight")
+
+	wearEnabled  = flag.Bool("wear", false, "count the write-backs of every page and estimate the lifetime of the flash")
+	wearCapacity = flag.Int("wear-capacity", 0, "pages of the flash device (0 for the footprint of the trace, which needs the trace in memory)")
+	wearCycles   = flag.Int("wear-pe", 3000, "program/erase cycles each flash block endures")
+	wearTop      = flag.Int("wear-top", 10, "number of hottest pages reported")
+)
+
+// probes are the observers attached to the simulator of one job, each one
+// enabled by its own flags.
+type probes struct {
+	tracer  *tracer
+	ftl     *ftl.FTL
+	latency *latency.Model
+	wear    *wear.Counter
+}
+
+func newProbes(j job, sim simulator.Simulator) (p *probes, err error) {
+	p = &probes{}
+	observable, ok := sim.(simulator.Observable)
+	if !ok {
+		return p, nil
+	}
+
+	var observers simulator.Observers
+	if events != nil {
+		p.tracer = &tracer{job: j}
+		observers = append(observers, p.tracer)
+	}
+	if *ftlEnabled {
+		if p.ftl, err = newFTL(j.footprint); err != nil {
+			return nil, err
+		}
+		observers = append(observers, p.ftl)
+	}
+	if *latencyEnabled {
+		if p.latency, err = newLatencyModel(); err != nil {
+			return nil, err
+		}
+		if p.ftl != nil {
+			p.latency.SetFlash(p.ftl)
+		}
+		observers = append(observers, p.latency)
+	}
+	if *wearEnabled {
+		p.wear = wear.NewCounter()
+		observers = append(observers, p.wear)
+	}
+	if len(observers) > 0 {
+		observable.SetObserver(observers)
+	}
+	return p, nil
+}
+
+// newFTL builds the SSD behind the cache, as large as footprint pages unless
+// -ftl-capacity says otherwise.
+func newFTL(footprint int) (*ftl.FTL, error) {
+	capacity := *ftlCapacity
+	if capacity == 0 {
+		capacity = footprint
+	}
+	return ftl.New(ftl.Config{
+		LogicalPages:     capacity,
+		PagesPerBlock:    *ftlPagesPerBlock,
+		OverProvisioning: *ftlOverProvision,
+		GC:               *ftlGC,
+	})
+}
+
+func newLatencyModel() (*latency.Model, error) {
+	return latency.New(latency.Config{
+		Hit:        *latencyHit,
+		Read:       *latencyRead,
+		Program:    *latencyProgram,
+		Erase:      *latencyErase,
+		QueueDepth: *latencyQD,
+	})
+}
+
+// wearModel describes flash of footprint pages unless -wear-capacity says
+// otherwise.
+func wearModel(footprint int) wear.Config {
+	capacity := *wearCapacity
+	if capacity == 0 {
+		capacity = footprint
+	}
+	return wear.Config{Capacity: capacity, PECycles: *wearCycles, Top: *wearTop}
+}
+
+// at must be called before the access at trace index is simulated.
+func (p *probes) at(index int, op simulator.Op) {
+	p.tracer.at(index, op)
+	if p.latency != nil {
+		p.latency.Begin(op)
+	}
+}
+
+// fill adds what the probes of j measured to result.
+func (p *probes) fill(j job, result *report.Result) error {
+	if p.ftl != nil {
+		if err := p.ftl.Err(); err != nil {
+			return err
+		}
+		stats := p.ftl.Stats()
+		result.FTL = &stats
+	}
+	if p.latency != nil {
+		stats := p.latency.Stats()
+		result.Latency = &stats
+	}
+	if p.wear != nil {
+		writeAmplification := 1.0
+		if result.FTL != nil && result.FTL.HostWrites > 0 {
+			writeAmplification = result.FTL.WriteAmplification
+		}
+		stats := p.wear.Stats(wearModel(j.footprint), writeAmplification)
+		result.Wear = &stats
+	}
+	return nil
+}
+
+// footprint returns the number of distinct blocks accessed by traces.
+func footprint(traces []simulator.Trace) int {
+	blocks := make(map[int]bool)
+	for _, trace := range traces {
+		if trace.Op != simulator.OpFlush {
+			blocks[trace.Addr] = true
+		}
+	}
+	return len(blocks)
+}
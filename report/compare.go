@@ -0,0 +1,42 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Compare writes a table with one line per algorithm and cache size, grouped
+// by cache size so the algorithms can be read side by side.
+func Compare(w io.Writer, results []Result) error {
+	sorted := make([]Result, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Stats.CacheSize < sorted[j].Stats.CacheSize
+	})
+
+	// the energy column only appears when an energy model was applied
+	withEnergy := len(sorted) > 0 && sorted[0].Energy != nil
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "cache size\talgorithm\thit ratio\twrite count\truntime (s)\t")
+	if withEnergy {
+		fmt.Fprint(tw, "energy (J)\t")
+	}
+	fmt.Fprintln(tw)
+	for _, result := range sorted {
+		fmt.Fprintf(tw, "%d\t%s\t%.4f%%\t%d\t%.4f\t",
+			result.Stats.CacheSize,
+			result.Algorithm,
+			100*result.Stats.HitRatio(),
+			result.Stats.FlashWrites,
+			result.Duration.Seconds(),
+		)
+		if withEnergy {
+			fmt.Fprintf(tw, "%.4f\t", result.Energy.Total)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Lifetime writes a table of the estimated flash lifetime of every result
+// that counted its write-backs, grouped by cache size. Each lifetime is also
+// given relative to the first algorithm at the same cache size.
+func Lifetime(w io.Writer, results []Result) error {
+	sorted := make([]Result, 0, len(results))
+	for _, result := range results {
+		if result.Wear != nil {
+			sorted = append(sorted, result)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Stats.CacheSize < sorted[j].Stats.CacheSize
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "cache size\talgorithm\twrite-backs\tmax per page\tlifetime (replays)\trelative\t")
+	var base *Result
+	for i, result := range sorted {
+		if base == nil || base.Stats.CacheSize != result.Stats.CacheSize {
+			base = &sorted[i]
+		}
+		relative := "-"
+		if result.Wear.Lifetime > 0 && base.Wear.Lifetime > 0 {
+			relative = fmt.Sprintf("%.2fx", result.Wear.Lifetime/base.Wear.Lifetime)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%.1f\t%s\t\n",
+			result.Stats.CacheSize,
+			result.Algorithm,
+			result.Wear.Writes,
+			result.Wear.MaxWrites,
+			result.Wear.Lifetime,
+			relative,
+		)
+	}
+	return tw.Flush()
+}
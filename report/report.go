@@ -0,0 +1,294 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang/energy"
+	"golang/ftl"
+	"golang/latency"
+	"golang/simulator"
+	"golang/wear"
+)
+
+// Result holds the outcome of simulating one algorithm at one cache size.
+type Result struct {
+	Algorithm string
+	Trace     string
+	Stats     simulator.Stats
+	Duration  time.Duration
+	// TargetHistory is only set for adaptive policies such as ARC.
+	TargetHistory []simulator.Sample
+	// FTL is only set when the write-back stream was fed to a simulated SSD.
+	FTL *ftl.Stats
+	// Latency is only set when a device timing model was applied.
+	Latency *latency.Stats
+	// Energy is only set when an energy model was applied.
+	Energy *energy.Stats
+	// Wear is only set when the write-backs of every page were counted.
+	Wear *wear.Stats
+}
+
+// Formatter writes a set of results in a particular output format.
+type Formatter interface {
+	Format(w io.Writer, results []Result) error
+	Extension() string
+}
+
+// NewFormatter returns the formatter registered under name (text, json or csv).
+func NewFormatter(name string) (Formatter, error) {
+	switch strings.ToLower(name) {
+	case "text", "txt":
+		return textFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "csv":
+		return csvFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown report format %q", name)
+}
+
+type row struct {
+	Algorithm      string  `json:"algorithm"`
+	Trace          string  `json:"trace"`
+	CacheSize      int     `json:"cache_size"`
+	Hits           int     `json:"hits"`
+	Misses         int     `json:"misses"`
+	HitRatio       float64 `json:"hit_ratio"`
+	ReadHits       int     `json:"read_hits"`
+	ReadMisses     int     `json:"read_misses"`
+	WriteHits      int     `json:"write_hits"`
+	DirtyEvictions int     `json:"dirty_evictions"`
+	CleanEvictions int     `json:"clean_evictions"`
+	FlashWrites    int     `json:"flash_writes"`
+	ResidentSize   int     `json:"resident_size"`
+	StackSize      int     `json:"stack_size"`
+	ListSize       int     `json:"list_size"`
+	Duration       float64 `json:"duration_seconds"`
+
+	TargetHistory []simulator.Sample `json:"target_history,omitempty"`
+	FTL           *ftl.Stats         `json:"ftl,omitempty"`
+	Latency       *latency.Stats     `json:"latency,omitempty"`
+	Energy        *energy.Stats      `json:"energy,omitempty"`
+	Wear          *wear.Stats        `json:"wear,omitempty"`
+}
+
+func newRow(result Result) row {
+	stats := result.Stats
+	return row{
+		Algorithm:      result.Algorithm,
+		Trace:          result.Trace,
+		CacheSize:      stats.CacheSize,
+		Hits:           stats.Hits,
+		Misses:         stats.Misses,
+		HitRatio:       stats.HitRatio(),
+		ReadHits:       stats.ReadHits,
+		ReadMisses:     stats.ReadMisses,
+		WriteHits:      stats.WriteHits,
+		DirtyEvictions: stats.DirtyEvictions,
+		CleanEvictions: stats.CleanEvictions,
+		FlashWrites:    stats.FlashWrites,
+		ResidentSize:   stats.ResidentSize,
+		StackSize:      stats.StackSize,
+		ListSize:       stats.ListSize,
+		Duration:       result.Duration.Seconds(),
+		TargetHistory:  result.TargetHistory,
+		FTL:            result.FTL,
+		Latency:        result.Latency,
+		Energy:         result.Energy,
+		Wear:           result.Wear,
+	}
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Extension() string { return "json" }
+
+func (jsonFormatter) Format(w io.Writer, results []Result) error {
+	rows := make([]row, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, newRow(result))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+var csvHeader = []string{
+	"algorithm", "trace", "cache_size", "hits", "misses", "hit_ratio",
+	"read_hits", "read_misses", "write_hits", "dirty_evictions", "clean_evictions",
+	"flash_writes", "resident_size", "stack_size", "list_size", "duration_seconds",
+	"ftl_write_amplification", "ftl_gc_copies", "ftl_erases", "ftl_max_block_erases",
+	"mean_latency_us", "p99_latency_us", "io_time_seconds", "elapsed_seconds",
+	"energy_joules", "lifetime_replays", "lifetime_replays_unleveled", "max_writes_per_page",
+}
+
+type csvFormatter struct{}
+
+func (csvFormatter) Extension() string { return "csv" }
+
+func (csvFormatter) Format(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		r := newRow(result)
+		record := []string{
+			r.Algorithm,
+			r.Trace,
+			strconv.Itoa(r.CacheSize),
+			strconv.Itoa(r.Hits),
+			strconv.Itoa(r.Misses),
+			strconv.FormatFloat(r.HitRatio, 'f', 6, 64),
+			strconv.Itoa(r.ReadHits),
+			strconv.Itoa(r.ReadMisses),
+			strconv.Itoa(r.WriteHits),
+			strconv.Itoa(r.DirtyEvictions),
+			strconv.Itoa(r.CleanEvictions),
+			strconv.Itoa(r.FlashWrites),
+			strconv.Itoa(r.ResidentSize),
+			strconv.Itoa(r.StackSize),
+			strconv.Itoa(r.ListSize),
+			strconv.FormatFloat(r.Duration, 'f', 6, 64),
+		}
+		if r.FTL != nil {
+			record = append(record,
+				strconv.FormatFloat(r.FTL.WriteAmplification, 'f', 6, 64),
+				strconv.Itoa(r.FTL.GCCopies),
+				strconv.Itoa(r.FTL.Erases),
+				strconv.Itoa(maxInt(r.FTL.BlockErases)),
+			)
+		} else {
+			record = append(record, "", "", "", "")
+		}
+		if r.Latency != nil {
+			record = append(record,
+				strconv.FormatFloat(r.Latency.MeanLatency, 'f', 3, 64),
+				strconv.FormatFloat(r.Latency.P99Latency, 'f', 3, 64),
+				strconv.FormatFloat(r.Latency.IOTime, 'f', 6, 64),
+				strconv.FormatFloat(r.Latency.Elapsed, 'f', 6, 64),
+			)
+		} else {
+			record = append(record, "", "", "", "")
+		}
+		if r.Energy != nil {
+			record = append(record, strconv.FormatFloat(r.Energy.Total, 'f', 6, 64))
+		} else {
+			record = append(record, "")
+		}
+		if r.Wear != nil {
+			record = append(record,
+				strconv.FormatFloat(r.Wear.Lifetime, 'f', 3, 64),
+				strconv.FormatFloat(r.Wear.LifetimeUnleveled, 'f', 3, 64),
+				strconv.Itoa(r.Wear.MaxWrites),
+			)
+		} else {
+			record = append(record, "", "", "")
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// textFormatter writes every result in the layout LIRS used to print,
+// including the "!ALGORITHM|size|hit|accesses" summary line. This changed the
+// text output of the other policies: LRU's "cache size: N" lines are gone and
+// LIRSWSR is headed LIRSWSR rather than LIRSWSRVERSIA. The lir and hir
+// capacity lines are dropped for every policy: they describe LIRS internals
+// no other policy has.
+type textFormatter struct{}
+
+func (textFormatter) Extension() string { return "txt" }
+
+func (textFormatter) Format(w io.Writer, results []Result) error {
+	for _, result := range results {
+		stats := result.Stats
+		name := strings.ToUpper(result.Algorithm)
+		_, err := fmt.Fprintf(w, `_______________________________________________________
+%v
+cache size : %v
+cache hit : %v
+cache miss : %v
+hit ratio : %v
+list size : %v
+stack size : %v
+write count : %v
+duration : %v
+!%v|%v|%v|%v
+`, name, stats.CacheSize, stats.Hits, stats.Misses, float32(100*stats.HitRatio()), stats.ListSize, stats.StackSize, stats.FlashWrites, result.Duration.Seconds(), name, stats.CacheSize, stats.Hits, stats.Accesses())
+		if err != nil {
+			return err
+		}
+		if f := result.FTL; f != nil {
+			_, err = fmt.Fprintf(w, "ftl host writes : %v\nftl write amplification : %.4f\nftl gc copies : %v\nftl erases : %v (max %v per block)\n",
+				f.HostWrites, f.WriteAmplification, f.GCCopies, f.Erases, maxInt(f.BlockErases))
+			if err != nil {
+				return err
+			}
+		}
+		if l := result.Latency; l != nil {
+			_, err = fmt.Fprintf(w, "mean latency : %.3f us\np99 latency : %.3f us\nio time : %.6f s\nelapsed : %.6f s (%.0f IOPS)\n",
+				l.MeanLatency, l.P99Latency, l.IOTime, l.Elapsed, l.IOPS)
+			if err != nil {
+				return err
+			}
+		}
+		if e := result.Energy; e != nil {
+			_, err = fmt.Fprintf(w, "energy : %.6f J (dram %.6f, read %.6f, program %.6f, erase %.6f)\n",
+				e.Total, e.DRAM, e.Read, e.Program, e.Erase)
+			if err != nil {
+				return err
+			}
+		}
+		if wr := result.Wear; wr != nil {
+			_, err = fmt.Fprintf(w, "pages written : %v\nwrites per page : mean %.2f, p50 %v, p90 %v, p99 %v, max %v\nlifetime : %.1f trace replays (%.1f without wear leveling)\n",
+				wr.PagesWritten, wr.MeanWrites, wr.P50Writes, wr.P90Writes, wr.P99Writes, wr.MaxWrites, wr.Lifetime, wr.LifetimeUnleveled)
+			if err != nil {
+				return err
+			}
+			for _, page := range wr.Hottest {
+				if _, err = fmt.Fprintf(w, "hot page : %v written %v times\n", page.Addr, page.Writes); err != nil {
+					return err
+				}
+			}
+		}
+		if history := result.TargetHistory; len(history) > 0 {
+			// the full history can hold a sample per access: only the JSON
+			// report carries it
+			low, high := history[0].Value, history[0].Value
+			for _, sample := range history {
+				if sample.Value < low {
+					low = sample.Value
+				}
+				if sample.Value > high {
+					high = sample.Value
+				}
+			}
+			last := history[len(history)-1]
+			_, err = fmt.Fprintf(w, "target : %v at access %v (min %v, max %v over %v samples)\n",
+				last.Value, last.Access, low, high, len(history))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func maxInt(values []int) (max int) {
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// SampleError compares the hit ratios of a sampled run with those of a full
+// run of the same configurations, given in the same order, and writes the
+// absolute error of each one followed by their mean.
+func SampleError(w io.Writer, full, sampled []Result) error {
+	if len(full) != len(sampled) {
+		return fmt.Errorf("%d full results for %d sampled results", len(full), len(sampled))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "cache size\talgorithm\tfull hit ratio\tsampled hit ratio\terror\tspeedup\t")
+	total := 0.0
+	for i := range full {
+		diff := math.Abs(full[i].Stats.HitRatio() - sampled[i].Stats.HitRatio())
+		total += diff
+		fmt.Fprintf(tw, "%d\t%s\t%.4f%%\t%.4f%%\t%.4f%%\t%.1fx\t\n",
+			full[i].Stats.CacheSize,
+			full[i].Algorithm,
+			100*full[i].Stats.HitRatio(),
+			100*sampled[i].Stats.HitRatio(),
+			100*diff,
+			full[i].Duration.Seconds()/sampled[i].Duration.Seconds(),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(full) > 0 {
+		_, err := fmt.Fprintf(w, "mean absolute error: %.4f%%\n", 100*total/float64(len(full)))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package simulator
+
+import "fmt"
+
+// EventKind says what happened to a block during an access.
+type EventKind int
+
+const (
+	// every access produces exactly one of the four events below
+	EventHit EventKind = iota
+	EventHitLIR
+	EventHitResidentHIR
+	EventMiss
+
+	EventPromotedToLIR
+	EventDemotedToHIR
+	EventEvictedClean
+	EventEvictedDirty
+	// EventFlushed is a dirty page written back by a flush; it stays cached.
+	EventFlushed
+	// EventWrittenThrough is a write sent straight to flash by a cache with
+	// no room for the page.
+	EventWrittenThrough
+	EventTrimmed
+	EventPrunedFromStack
+	EventColdFlagSet
+	EventColdFlagCleared
+)
+
+var eventNames = [...]string{
+	EventHit:             "hit",
+	EventHitLIR:          "hit_lir",
+	EventHitResidentHIR:  "hit_resident_hir",
+	EventMiss:            "miss",
+	EventPromotedToLIR:   "promoted_to_lir",
+	EventDemotedToHIR:    "demoted_to_hir",
+	EventEvictedClean:    "evicted_clean",
+	EventEvictedDirty:    "evicted_dirty",
+	EventFlushed:         "flushed",
+	EventWrittenThrough:  "written_through",
+	EventTrimmed:         "trimmed",
+	EventPrunedFromStack: "pruned_from_stack",
+	EventColdFlagSet:     "cold_flag_set",
+	EventColdFlagCleared: "cold_flag_cleared",
+}
+
+func (k EventKind) String() string {
+	if k >= 0 && int(k) < len(eventNames) {
+		return eventNames[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// IsHit reports whether k is one of the hit events.
+func (k EventKind) IsHit() bool {
+	return k == EventHit || k == EventHitLIR || k == EventHitResidentHIR
+}
+
+// IsWriteBack reports whether k wrote a page to flash.
+func (k EventKind) IsWriteBack() bool {
+	return k == EventEvictedDirty || k == EventFlushed || k == EventWrittenThrough
+}
+
+type Event struct {
+	Kind EventKind
+	Addr int
+}
+
+// Observer receives the events of a simulator as they happen.
+type Observer interface {
+	Observe(Event)
+}
+
+// Observable is implemented by simulators that report events.
+type Observable interface {
+	SetObserver(Observer)
+}
+
+// Observers passes every event to each of its observers in turn.
+type Observers []Observer
+
+func (o Observers) Observe(e Event) {
+	for _, observer := range o {
+		observer.Observe(e)
+	}
+}
+
+// Notifier is embedded by policies to make them Observable. Notify is a no-op
+// until an observer is set.
+type Notifier struct {
+	observer Observer
+}
+
+func (n *Notifier) SetObserver(observer Observer) {
+	n.observer = observer
+}
+
+func (n *Notifier) Notify(kind EventKind, addr int) {
+	if n.observer != nil {
+		n.observer.Observe(Event{Kind: kind, Addr: addr})
+	}
+}
@@ -0,0 +1,78 @@
+// Package simtest holds test cases shared by the simulators that implement
+// the same policy, such as LIRS and LIRS-WSR.
+package simtest
+
+import (
+	"testing"
+
+	"golang/simulator"
+)
+
+// Simulator is a simulator that can check its internal consistency.
+type Simulator interface {
+	simulator.Simulator
+	simulator.InvariantChecker
+}
+
+// HitTest replays Traces, then expects Access to hit or miss.
+type HitTest struct {
+	Name   string
+	Traces []simulator.Trace
+	Access simulator.Trace
+	Hit    bool
+}
+
+// LIRSHitTests are for a LIRS cache of 200 blocks with 1% of them, two
+// blocks, for resident HIR blocks, which leaves 198 LIR blocks.
+var LIRSHitTests = []HitTest{
+	// 1 to 198 fill the LIR blocks, 199 to 201 pass through the HIR blocks
+	{"resident HIR block", reads(1, 201), simulator.Trace{Addr: 200}, true},
+	{"evicted HIR block", reads(1, 201), simulator.Trace{Addr: 199}, false},
+	{"trimmed LIR block", trim(reads(1, 201), 5), simulator.Trace{Addr: 5}, false},
+	// the trim frees LIR space, which must not cost the resident HIR block
+	{"resident HIR block after a trim", trim(reads(1, 201), 5), simulator.Trace{Addr: 200}, true},
+	{"LIR block after a trim", trim(reads(1, 201), 5), simulator.Trace{Addr: 6}, true},
+}
+
+// reads returns a read of every address from first to last.
+func reads(first, last int) (traces []simulator.Trace) {
+	for addr := first; addr <= last; addr++ {
+		traces = append(traces, simulator.Trace{Addr: addr})
+	}
+	return traces
+}
+
+func trim(traces []simulator.Trace, addr int) []simulator.Trace {
+	return append(traces, simulator.Trace{Addr: addr, Op: simulator.OpTrim})
+}
+
+// RunHitTests runs every test on a simulator built by newSim, checking its
+// invariants after each access.
+func RunHitTests(t *testing.T, newSim func() Simulator, tests []HitTest) {
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			sim := newSim()
+			for i, trace := range tt.Traces {
+				if err := sim.Get(trace); err != nil {
+					t.Fatalf("access %d: %v", i, err)
+				}
+				if err := sim.CheckInvariants(); err != nil {
+					t.Fatalf("access %d: %v", i, err)
+				}
+			}
+
+			before := sim.Stats()
+			if err := sim.Get(tt.Access); err != nil {
+				t.Fatal(err)
+			}
+			if err := sim.CheckInvariants(); err != nil {
+				t.Fatal(err)
+			}
+			after := sim.Stats()
+			if hit := after.Hits > before.Hits; hit != tt.Hit || after.Accesses() != before.Accesses()+1 {
+				t.Errorf("hits %d -> %d, misses %d -> %d; want hit %v",
+					before.Hits, after.Hits, before.Misses, after.Misses, tt.Hit)
+			}
+		})
+	}
+}
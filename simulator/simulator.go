@@ -1,16 +1,117 @@
 package simulator
 
 import (
-	"os"
-	"time"
+	"fmt"
+	"strings"
 )
 
 type Simulator interface {
 	Get(Trace) error
-	PrintToFile(file *os.File, start time.Time) error
+	Stats() Stats
 }
 
+// Trace is a single request. When Size is zero Addr is a page address;
+// otherwise Addr is a byte offset and the request covers Size bytes, to be
+// split into pages before it reaches a Simulator.
 type Trace struct {
 	Addr int
-	Op   string
+	Op   Op
+	Size int
+}
+
+// Op is the kind of request a Trace carries.
+type Op int
+
+const (
+	OpRead Op = iota
+	OpWrite
+	// OpTrim discards a page: a cached copy is dropped without being written back.
+	OpTrim
+	// OpFlush asks the cache to write back its dirty pages; Addr is ignored.
+	OpFlush
+)
+
+// ParseOp accepts the spellings found in the traces we use: single letters
+// ("R", "w"), words ("Read", "Discard") and the numeric codes 0 and 1.
+func ParseOp(s string) (Op, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "r", "read", "0":
+		return OpRead, nil
+	case "w", "write", "1":
+		return OpWrite, nil
+	case "t", "trim", "d", "discard":
+		return OpTrim, nil
+	case "f", "flush":
+		return OpFlush, nil
+	}
+	return OpRead, fmt.Errorf("unknown operation %q", s)
+}
+
+func (op Op) String() string {
+	switch op {
+	case OpRead:
+		return "R"
+	case OpWrite:
+		return "W"
+	case OpTrim:
+		return "T"
+	case OpFlush:
+		return "F"
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// Stats is a snapshot of the counters kept by a Simulator.
+type Stats struct {
+	CacheSize int
+	Hits      int
+	Misses    int
+	ReadHits  int
+	// ReadMisses are the misses that must fetch their page from flash; a
+	// write miss overwrites the page without reading it.
+	ReadMisses     int
+	WriteHits      int
+	DirtyEvictions int
+	CleanEvictions int
+	FlashWrites    int
+	ResidentSize   int
+	StackSize      int
+	ListSize       int
+}
+
+// Accesses returns the number of requests seen by the simulator.
+func (s Stats) Accesses() int {
+	return s.Hits + s.Misses
+}
+
+// HitRatio returns the hit ratio as a fraction between 0 and 1.
+func (s Stats) HitRatio() float64 {
+	if s.Accesses() == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Accesses())
+}
+
+// Sample is the value of a policy parameter after a given number of accesses.
+type Sample struct {
+	Access int `json:"access"`
+	Value  int `json:"value"`
+}
+
+// Adaptive is implemented by policies that tune a target size while they run
+// and record how it evolved.
+type Adaptive interface {
+	TargetHistory() []Sample
+}
+
+// InvariantChecker is implemented by policies that can verify the
+// consistency of their internal state.
+type InvariantChecker interface {
+	CheckInvariants() error
+}
+
+// TraceReader yields trace records one at a time. Next returns io.EOF once
+// the trace is exhausted.
+type TraceReader interface {
+	Next() (Trace, error)
 }
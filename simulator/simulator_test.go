@@ -0,0 +1,32 @@
+package simulator
+
+import "testing"
+
+func TestParseOp(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Op
+		wantErr bool
+	}{
+		{"R", OpRead, false},
+		{"read", OpRead, false},
+		{"0", OpRead, false},
+		{" w ", OpWrite, false},
+		{"Write", OpWrite, false},
+		{"1", OpWrite, false},
+		{"T", OpTrim, false},
+		{"Discard", OpTrim, false},
+		{"d", OpTrim, false},
+		{"F", OpFlush, false},
+		{"flush", OpFlush, false},
+		{"", OpRead, true},
+		{"2", OpRead, true},
+		{"erase", OpRead, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOp(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOp(%q) = %v, %v; want %v, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
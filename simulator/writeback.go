@@ -0,0 +1,74 @@
+package simulator
+
+import "sort"
+
+// WriteBack models a write-back cache in front of flash. A write marks the
+// page dirty; evicting or flushing a dirty page costs one flash write, while
+// evicting a clean page costs nothing. Policies report the page movements,
+// WriteBack does the accounting, so every policy's write count means the same
+// thing. Evictions, flushes, write-throughs and trims are reported through
+// notifier.
+type WriteBack struct {
+	DirtyEvictions int
+	CleanEvictions int
+	FlashWrites    int
+
+	dirty    map[int]bool
+	notifier *Notifier
+}
+
+func NewWriteBack(notifier *Notifier) *WriteBack {
+	return &WriteBack{dirty: make(map[int]bool), notifier: notifier}
+}
+
+// Access records a read or write to a resident page.
+func (wb *WriteBack) Access(addr int, op Op) {
+	if op == OpWrite {
+		wb.dirty[addr] = true
+	}
+}
+
+// IsDirty reports whether addr holds data not yet written to flash.
+func (wb *WriteBack) IsDirty(addr int) bool {
+	return wb.dirty[addr]
+}
+
+// Evict records that addr left the cache, writing it back if it is dirty.
+func (wb *WriteBack) Evict(addr int) {
+	if wb.dirty[addr] {
+		delete(wb.dirty, addr)
+		wb.DirtyEvictions++
+		wb.FlashWrites++
+		wb.notifier.Notify(EventEvictedDirty, addr)
+		return
+	}
+	wb.CleanEvictions++
+	wb.notifier.Notify(EventEvictedClean, addr)
+}
+
+// WriteThrough records a write to addr that the cache cannot hold, as when
+// it has no room at all: the page goes straight to flash.
+func (wb *WriteBack) WriteThrough(addr int) {
+	wb.FlashWrites++
+	wb.notifier.Notify(EventWrittenThrough, addr)
+}
+
+// Discard forgets addr without writing it back, as a TRIM does.
+func (wb *WriteBack) Discard(addr int) {
+	delete(wb.dirty, addr)
+	wb.notifier.Notify(EventTrimmed, addr)
+}
+
+// Flush writes back every dirty page; the pages stay cached, now clean.
+func (wb *WriteBack) Flush() {
+	addrs := make([]int, 0, len(wb.dirty))
+	for addr := range wb.dirty {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	for _, addr := range addrs {
+		wb.notifier.Notify(EventFlushed, addr)
+	}
+	wb.FlashWrites += len(wb.dirty)
+	wb.dirty = make(map[int]bool)
+}
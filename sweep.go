@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"golang/report"
+	"golang/simulator"
+	"golang/tracefile"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// job is one configuration to simulate. When sampler is set the simulator is
+// fed the sampled trace with a cache scaled down to match, but results are
+// still reported under cacheSize. footprint is the number of distinct pages
+// of the trace replayed, sizing the flash when no capacity is given.
+type job struct {
+	algorithm string
+	cacheSize int
+	sampler   *tracefile.Sampler
+	footprint int
+}
+
+func newJobs(algorithms []string, cacheList []int, sampler *tracefile.Sampler) (jobs []job) {
+	for _, algorithm := range algorithms {
+		for _, cache := range cacheList {
+			jobs = append(jobs, job{algorithm: algorithm, cacheSize: cache, sampler: sampler})
+		}
+	}
+	return jobs
+}
+
+func (j job) simulatedSize() int {
+	if j.sampler == nil {
+		return j.cacheSize
+	}
+	return j.sampler.Scale(j.cacheSize)
+}
+
+// sweep simulates every job against traces using up to workers goroutines.
+// Results are returned in the same order as jobs regardless of which worker
+// finishes first; traces is only ever read.
+func sweep(jobs []job, traces []simulator.Trace, traceName string, workers int) ([]report.Result, error) {
+	var (
+		results = make([]report.Result, len(jobs))
+		errs    = make([]error, len(jobs))
+		indexes = make(chan int)
+		wg      sync.WaitGroup
+		pages   int
+	)
+	if *ftlEnabled || *wearEnabled {
+		pages = footprint(traces)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				j := jobs[i]
+				j.footprint = pages
+				results[i], errs[i] = runJob(j, traces, traceName)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func runJob(j job, traces []simulator.Trace, traceName string) (result report.Result, err error) {
+	sim, err := newSimulator(j.algorithm, j.simulatedSize(), traces)
+	if err != nil {
+		return result, err
+	}
+	p, err := newProbes(j, sim)
+	if err != nil {
+		return result, err
+	}
+
+	timeStart := time.Now()
+	for i, trace := range traces {
+		p.at(i, trace.Op)
+		if err = sim.Get(trace); err != nil {
+			return result, err
+		}
+		if err = check(j, sim, i); err != nil {
+			return result, err
+		}
+	}
+	if *flushAtEnd {
+		p.at(len(traces), simulator.OpFlush)
+		if err = sim.Get(simulator.Trace{Op: simulator.OpFlush}); err != nil {
+			return result, err
+		}
+	}
+
+	return newResult(j, sim, p, traceName, time.Since(timeStart))
+}
+
+// check verifies the internal state of sim after the access at trace index
+// when running with -paranoid and sim knows how to check itself.
+func check(j job, sim simulator.Simulator, index int) error {
+	if !*paranoid {
+		return nil
+	}
+	checker, ok := sim.(simulator.InvariantChecker)
+	if !ok {
+		return nil
+	}
+	if err := checker.CheckInvariants(); err != nil {
+		return fmt.Errorf("%v with cache size %d: invariant broken at trace index %d: %v", j.algorithm, j.cacheSize, index, err)
+	}
+	return nil
+}
+
+func newResult(j job, sim simulator.Simulator, p *probes, traceName string, duration time.Duration) (report.Result, error) {
+	result := report.Result{
+		Algorithm: j.algorithm,
+		Trace:     traceName,
+		Stats:     sim.Stats(),
+		Duration:  duration,
+	}
+	result.Stats.CacheSize = j.cacheSize
+	if adaptive, ok := sim.(simulator.Adaptive); ok {
+		result.TargetHistory = adaptive.TargetHistory()
+	}
+	if err := p.fill(j, &result); err != nil {
+		return result, fmt.Errorf("%v with cache size %d: %v", j.algorithm, j.cacheSize, err)
+	}
+	if *energyEnabled {
+		e := energyModel().Estimate(result.Stats, result.FTL)
+		result.Energy = &e
+	}
+	return result, nil
+}
+
+func streamFile(jobs []job, filePath string, format string, pageSize int, sampler *tracefile.Sampler, traceName string) ([]report.Result, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := tracefile.NewFormatReader(file, format)
+	if err != nil {
+		return nil, err
+	}
+	var pages simulator.TraceReader = tracefile.NewPageReader(reader, pageSize)
+	if sampler != nil {
+		pages = tracefile.NewSampleReader(pages, sampler)
+	}
+	return fanOut(jobs, pages, traceName)
+}
+
+// fanOut feeds every record read from reader to one simulator per job, so the
+// trace is read exactly once no matter how many configurations are compared.
+func fanOut(jobs []job, reader simulator.TraceReader, traceName string) ([]report.Result, error) {
+	var (
+		sims      = make([]simulator.Simulator, len(jobs))
+		probes    = make([]*probes, len(jobs))
+		durations = make([]time.Duration, len(jobs))
+		results   = make([]report.Result, len(jobs))
+		err       error
+	)
+
+	for i, j := range jobs {
+		if sims[i], err = newSimulator(j.algorithm, j.simulatedSize(), nil); err != nil {
+			return nil, err
+		}
+		if probes[i], err = newProbes(j, sims[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	index := 0
+	for ; ; index++ {
+		trace, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, sim := range sims {
+			probes[i].at(index, trace.Op)
+			timeStart := time.Now()
+			if err = sim.Get(trace); err != nil {
+				return nil, err
+			}
+			durations[i] += time.Since(timeStart)
+			if err = check(jobs[i], sim, index); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if *flushAtEnd {
+		for i, sim := range sims {
+			probes[i].at(index, simulator.OpFlush)
+			timeStart := time.Now()
+			if err = sim.Get(simulator.Trace{Op: simulator.OpFlush}); err != nil {
+				return nil, err
+			}
+			durations[i] += time.Since(timeStart)
+		}
+	}
+
+	for i, j := range jobs {
+		if results[i], err = newResult(j, sims[i], probes[i], traceName, durations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
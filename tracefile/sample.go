@@ -0,0 +1,82 @@
+package tracefile
+
+import (
+	"fmt"
+	"math"
+
+	"golang/simulator"
+)
+
+const sampleModulus = 1 << 24
+
+// Sampler implements SHARDS spatial sampling: a block is kept when the hash of
+// its address falls under a threshold, so a fixed fraction of the blocks is
+// kept together with every access to them. A cache of size c*rate fed the
+// sampled trace approximates a cache of size c fed the full trace.
+type Sampler struct {
+	rate      float64
+	threshold uint64
+}
+
+func NewSampler(rate float64) (*Sampler, error) {
+	if rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("sample rate %v must be in (0, 1]", rate)
+	}
+	return &Sampler{rate: rate, threshold: uint64(rate * sampleModulus)}, nil
+}
+
+func (s *Sampler) Rate() float64 {
+	return s.rate
+}
+
+// Keep reports whether the accesses to addr belong to the sample.
+func (s *Sampler) Keep(addr int) bool {
+	// splitmix64 finalizer
+	h := uint64(addr) + 0x9e3779b97f4a7c15
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	h ^= h >> 31
+	return h%sampleModulus < s.threshold
+}
+
+// Scale returns the cache size to simulate on the sampled trace in place of
+// size, never less than one block.
+func (s *Sampler) Scale(size int) int {
+	scaled := int(math.Round(float64(size) * s.rate))
+	if scaled < 1 {
+		return 1
+	}
+	return scaled
+}
+
+// Filter returns the records of traces that belong to the sample. Flushes
+// are not tied to a block and are always kept.
+func (s *Sampler) Filter(traces []simulator.Trace) (sampled []simulator.Trace) {
+	for _, trace := range traces {
+		if trace.Op == simulator.OpFlush || s.Keep(trace.Addr) {
+			sampled = append(sampled, trace)
+		}
+	}
+	return sampled
+}
+
+// SampleReader passes through the records of r that belong to the sample.
+type SampleReader struct {
+	reader  simulator.TraceReader
+	sampler *Sampler
+}
+
+func NewSampleReader(r simulator.TraceReader, sampler *Sampler) *SampleReader {
+	return &SampleReader{reader: r, sampler: sampler}
+}
+
+func (r *SampleReader) Next() (trace simulator.Trace, err error) {
+	for {
+		if trace, err = r.reader.Next(); err != nil {
+			return trace, err
+		}
+		if trace.Op == simulator.OpFlush || r.sampler.Keep(trace.Addr) {
+			return trace, nil
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package tracefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang/simulator"
+)
+
+// Trace formats understood by NewFormatReader.
+const (
+	FormatAddr  = "addr"  // addr,op
+	FormatSPC   = "spc"   // ASU,LBA,size,opcode,timestamp (SPC-1, UMass)
+	FormatUMass = "umass" // alias of FormatSPC
+	FormatMSR   = "msr"   // timestamp,host,disk,type,offset,size,latency
+)
+
+const (
+	// PageSize is the default cache page size, in bytes.
+	PageSize = 4096
+	// SectorSize is the size of the logical blocks SPC traces address.
+	SectorSize = 512
+	// volumeShift separates the byte address spaces of different ASUs or
+	// disks so that the same offset on two volumes never maps to the same page.
+	volumeShift = 48
+)
+
+type parseFunc func(fields []string) (simulator.Trace, error)
+
+// Reader streams trace records from an underlying reader without keeping the
+// trace in memory.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+	parse   parseFunc
+}
+
+// NewReader reads the native "addr,op" format.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{scanner: bufio.NewScanner(r), parse: parseAddr}
+}
+
+// NewFormatReader reads r in the named trace format.
+func NewFormatReader(r io.Reader, format string) (*Reader, error) {
+	reader := NewReader(r)
+	switch strings.ToLower(format) {
+	case FormatAddr, "":
+	case FormatSPC, FormatUMass:
+		reader.parse = parseSPC
+	case FormatMSR:
+		reader.parse = parseMSR
+	default:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+	return reader, nil
+}
+
+func (r *Reader) Next() (trace simulator.Trace, err error) {
+	for {
+		if !r.scanner.Scan() {
+			if err = r.scanner.Err(); err != nil {
+				return trace, err
+			}
+			return trace, io.EOF
+		}
+		r.line++
+
+		text := strings.TrimSpace(r.scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		trace, err = r.parse(fields)
+		if err != nil {
+			return trace, fmt.Errorf("line %d: %v", r.line, err)
+		}
+		return trace, nil
+	}
+}
+
+func parseAddr(fields []string) (trace simulator.Trace, err error) {
+	if len(fields) < 2 {
+		return trace, fmt.Errorf("expected addr,op")
+	}
+	trace.Addr, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return trace, err
+	}
+	trace.Op, err = simulator.ParseOp(fields[1])
+	return trace, err
+}
+
+func parseSPC(fields []string) (trace simulator.Trace, err error) {
+	if len(fields) < 4 {
+		return trace, fmt.Errorf("expected ASU,LBA,size,opcode,timestamp")
+	}
+	asu, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return trace, err
+	}
+	lba, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return trace, err
+	}
+	trace.Size, err = parseSize(fields[2])
+	if err != nil {
+		return trace, err
+	}
+	trace.Addr = asu<<volumeShift | lba*SectorSize
+	trace.Op, err = simulator.ParseOp(fields[3])
+	return trace, err
+}
+
+func parseMSR(fields []string) (trace simulator.Trace, err error) {
+	if len(fields) < 6 {
+		return trace, fmt.Errorf("expected timestamp,host,disk,type,offset,size,latency")
+	}
+	disk, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return trace, err
+	}
+	offset, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return trace, err
+	}
+	trace.Size, err = parseSize(fields[5])
+	if err != nil {
+		return trace, err
+	}
+	trace.Addr = disk<<volumeShift | offset
+	trace.Op, err = simulator.ParseOp(fields[3])
+	return trace, err
+}
+
+// parseSize reads the byte size of a request. A zero-length request still
+// addresses the page at its offset, so it is counted as one byte: passed
+// through with no size, its byte offset would be taken for a page number.
+func parseSize(s string) (int, error) {
+	size, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("negative size %d", size)
+	}
+	if size == 0 {
+		return 1, nil
+	}
+	return size, nil
+}
+
+// PageReader splits requests that carry a byte offset and size into one
+// page-sized access per page they touch. Records without a size are passed
+// through untouched.
+type PageReader struct {
+	reader   simulator.TraceReader
+	pageSize int
+	pending  simulator.Trace
+	next     int
+	last     int
+}
+
+func NewPageReader(r simulator.TraceReader, pageSize int) *PageReader {
+	return &PageReader{reader: r, pageSize: pageSize, next: 1}
+}
+
+func (r *PageReader) Next() (trace simulator.Trace, err error) {
+	if r.next <= r.last {
+		trace = simulator.Trace{Addr: r.next, Op: r.pending.Op}
+		r.next++
+		return trace, nil
+	}
+
+	trace, err = r.reader.Next()
+	if err != nil || trace.Size <= 0 {
+		return trace, err
+	}
+
+	r.pending = trace
+	r.next = trace.Addr / r.pageSize
+	r.last = (trace.Addr + trace.Size - 1) / r.pageSize
+	return r.Next()
+}
+
+// ReadAll drains r into a slice.
+func ReadAll(r simulator.TraceReader) (traces []simulator.Trace, err error) {
+	for {
+		trace, err := r.Next()
+		if err == io.EOF {
+			return traces, nil
+		}
+		if err != nil {
+			return traces, err
+		}
+		traces = append(traces, trace)
+	}
+}
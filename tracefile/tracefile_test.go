@@ -0,0 +1,85 @@
+package tracefile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang/simulator"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   parseFunc
+		line    string
+		want    simulator.Trace
+		wantErr bool
+	}{
+		{"spc read", parseSPC, "0,10,4096,r,0.5", simulator.Trace{Addr: 10 * SectorSize, Size: 4096}, false},
+		{"spc write on ASU 1", parseSPC, "1,0,512,W,0.5", simulator.Trace{Addr: 1 << volumeShift, Size: 512, Op: simulator.OpWrite}, false},
+		{"spc without timestamp", parseSPC, "0,1,512,R", simulator.Trace{Addr: SectorSize, Size: 512}, false},
+		{"spc zero size is one byte", parseSPC, "0,8,0,r,0", simulator.Trace{Addr: 8 * SectorSize, Size: 1}, false},
+		{"spc negative size", parseSPC, "0,8,-1,r,0", simulator.Trace{}, true},
+		{"spc short", parseSPC, "0,8,512", simulator.Trace{}, true},
+		{"spc bad lba", parseSPC, "0,x,512,r", simulator.Trace{}, true},
+		{"spc bad opcode", parseSPC, "0,8,512,x", simulator.Trace{}, true},
+		{"msr read", parseMSR, "128166372003061629,hm,0,Read,8192,4096,152", simulator.Trace{Addr: 8192, Size: 4096}, false},
+		{"msr write on disk 2", parseMSR, "1,src,2,Write,100,10,1", simulator.Trace{Addr: 2<<volumeShift | 100, Size: 10, Op: simulator.OpWrite}, false},
+		{"msr zero size is one byte", parseMSR, "1,src,0,Write,4096,0,1", simulator.Trace{Addr: 4096, Size: 1, Op: simulator.OpWrite}, false},
+		{"msr short", parseMSR, "1,src,0,Write,4096", simulator.Trace{}, true},
+		{"msr bad disk", parseMSR, "1,src,d,Write,4096,512,1", simulator.Trace{}, true},
+		{"addr", parseAddr, "42,T", simulator.Trace{Addr: 42, Op: simulator.OpTrim}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(strings.Split(tt.line, ","))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		trace  string
+		want   []simulator.Trace
+	}{
+		{"addr records pass through", FormatAddr, "3,R\n5,W\n", []simulator.Trace{
+			{Addr: 3}, {Addr: 5, Op: simulator.OpWrite},
+		}},
+		{"request within a page", FormatSPC, "0,1,512,r\n", []simulator.Trace{
+			{Addr: 0},
+		}},
+		{"request across pages", FormatSPC, "0,7,1024,w\n", []simulator.Trace{
+			{Addr: 0, Op: simulator.OpWrite}, {Addr: 1, Op: simulator.OpWrite},
+		}},
+		{"aligned request", FormatMSR, "1,h,0,Read,8192,8192,1\n", []simulator.Trace{
+			{Addr: 2}, {Addr: 3},
+		}},
+		{"zero size request", FormatMSR, "1,h,0,Read,8192,0,1\n1,h,0,Write,4095,0,1\n", []simulator.Trace{
+			{Addr: 2}, {Addr: 0, Op: simulator.OpWrite},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewFormatReader(strings.NewReader(tt.trace), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadAll(NewPageReader(reader, PageSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
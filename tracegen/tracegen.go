@@ -0,0 +1,261 @@
+package tracegen
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang/simulator"
+)
+
+// Pattern produces the block addresses of a synthetic workload.
+type Pattern interface {
+	Next(r *rand.Rand) int
+}
+
+// Uniform picks any of Blocks blocks with the same probability.
+type Uniform struct {
+	Blocks int
+	Offset int
+}
+
+func (u *Uniform) Next(r *rand.Rand) int {
+	return u.Offset + r.Intn(u.Blocks)
+}
+
+// Zipf picks block k, counted from 0, with a probability proportional to
+// 1/(k+1)^Skew, so the lowest addresses are the hottest ones.
+type Zipf struct {
+	Offset int
+	cdf    []float64
+}
+
+func NewZipf(blocks int, skew float64, offset int) *Zipf {
+	z := &Zipf{Offset: offset, cdf: make([]float64, blocks)}
+	total := 0.0
+	for k := range z.cdf {
+		total += 1 / math.Pow(float64(k+1), skew)
+		z.cdf[k] = total
+	}
+	for k := range z.cdf {
+		z.cdf[k] /= total
+	}
+	return z
+}
+
+func (z *Zipf) Next(r *rand.Rand) int {
+	k := sort.SearchFloat64s(z.cdf, r.Float64())
+	if k == len(z.cdf) {
+		k--
+	}
+	return z.Offset + k
+}
+
+// Scan reads fresh blocks one after the other and never comes back to them.
+type Scan struct {
+	Offset int
+	next   int
+}
+
+func (s *Scan) Next(r *rand.Rand) int {
+	s.next++
+	return s.Offset + s.next - 1
+}
+
+// Loop reads Blocks consecutive blocks in order, over and over.
+type Loop struct {
+	Blocks int
+	Offset int
+	next   int
+}
+
+func (l *Loop) Next(r *rand.Rand) int {
+	addr := l.Offset + l.next
+	l.next = (l.next + 1) % l.Blocks
+	return addr
+}
+
+// Mixture draws every access from one of its patterns, chosen at random in
+// proportion to its weight.
+type Mixture struct {
+	Patterns []Pattern
+	Weights  []float64
+}
+
+func (m *Mixture) Next(r *rand.Rand) int {
+	total := 0.0
+	for _, w := range m.Weights {
+		total += w
+	}
+	x := r.Float64() * total
+	for i, w := range m.Weights {
+		if x < w {
+			return m.Patterns[i].Next(r)
+		}
+		x -= w
+	}
+	return m.Patterns[len(m.Patterns)-1].Next(r)
+}
+
+// Phase runs Pattern for Length accesses.
+type Phase struct {
+	Pattern Pattern
+	Length  int
+}
+
+// Generator is a simulator.TraceReader over a sequence of phases, each access
+// being a write with probability WriteFraction.
+type Generator struct {
+	phases        []Phase
+	writeFraction float64
+	rand          *rand.Rand
+	phase         int
+	done          int
+}
+
+func NewGenerator(phases []Phase, writeFraction float64, seed int64) *Generator {
+	return &Generator{
+		phases:        phases,
+		writeFraction: writeFraction,
+		rand:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (g *Generator) Next() (trace simulator.Trace, err error) {
+	for g.phase < len(g.phases) && g.done == g.phases[g.phase].Length {
+		g.phase++
+		g.done = 0
+	}
+	if g.phase == len(g.phases) {
+		return trace, io.EOF
+	}
+	g.done++
+
+	trace.Addr = g.phases[g.phase].Pattern.Next(g.rand)
+	trace.Op = simulator.OpRead
+	if g.rand.Float64() < g.writeFraction {
+		trace.Op = simulator.OpWrite
+	}
+	return trace, nil
+}
+
+// Parse reads a workload description of length accesses. Phases are separated
+// by ";" and may start with their own length followed by ":", the phases
+// without one sharing what is left of length. A phase is one pattern or a
+// mixture of weighted patterns joined by "+":
+//
+//	uniform(blocks[,offset])
+//	zipf(blocks,skew[,offset])
+//	scan([offset])
+//	loop(blocks[,offset])
+//
+// For example "20000:loop(500);0.9*zipf(10000,0.8)+0.1*scan(1000000)".
+func Parse(spec string, length int) ([]Phase, error) {
+	var (
+		phases []Phase
+		fixed  int
+		open   []int
+	)
+	for _, text := range strings.Split(spec, ";") {
+		var phase Phase
+		if i := strings.Index(text, ":"); i >= 0 {
+			n, err := strconv.Atoi(strings.TrimSpace(text[:i]))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("phase %q: invalid length", text)
+			}
+			phase.Length = n
+			fixed += n
+			text = text[i+1:]
+		} else {
+			open = append(open, len(phases))
+		}
+		pattern, err := parseMixture(text)
+		if err != nil {
+			return nil, fmt.Errorf("phase %q: %v", text, err)
+		}
+		phase.Pattern = pattern
+		phases = append(phases, phase)
+	}
+
+	if len(open) > 0 {
+		if fixed > length {
+			return nil, fmt.Errorf("phases of fixed length add up to %d accesses, more than %d", fixed, length)
+		}
+		for i, p := range open {
+			phases[p].Length = (length - fixed) / len(open)
+			if i < (length-fixed)%len(open) {
+				phases[p].Length++
+			}
+		}
+	}
+	return phases, nil
+}
+
+func parseMixture(text string) (Pattern, error) {
+	parts := strings.Split(text, "+")
+	if len(parts) == 1 {
+		return parsePattern(parts[0])
+	}
+
+	mixture := &Mixture{}
+	for _, part := range parts {
+		weight := 1.0
+		if i := strings.Index(part, "*"); i >= 0 {
+			w, err := strconv.ParseFloat(strings.TrimSpace(part[:i]), 64)
+			if err != nil || w < 0 {
+				return nil, fmt.Errorf("invalid weight in %q", part)
+			}
+			weight = w
+			part = part[i+1:]
+		}
+		pattern, err := parsePattern(part)
+		if err != nil {
+			return nil, err
+		}
+		mixture.Patterns = append(mixture.Patterns, pattern)
+		mixture.Weights = append(mixture.Weights, weight)
+	}
+	return mixture, nil
+}
+
+func parsePattern(text string) (Pattern, error) {
+	text = strings.TrimSpace(text)
+	open := strings.Index(text, "(")
+	if open < 0 || !strings.HasSuffix(text, ")") {
+		return nil, fmt.Errorf("pattern %q is not of the form name(arguments)", text)
+	}
+	name := strings.ToLower(text[:open])
+
+	var args []float64
+	if inner := strings.TrimSpace(text[open+1 : len(text)-1]); inner != "" {
+		for _, field := range strings.Split(inner, ",") {
+			arg, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %q: invalid argument %q", text, field)
+			}
+			args = append(args, arg)
+		}
+	}
+	arg := func(i int) int {
+		if i < len(args) {
+			return int(args[i])
+		}
+		return 0
+	}
+
+	switch {
+	case name == "uniform" && (len(args) == 1 || len(args) == 2) && arg(0) > 0:
+		return &Uniform{Blocks: arg(0), Offset: arg(1)}, nil
+	case name == "zipf" && (len(args) == 2 || len(args) == 3) && arg(0) > 0 && args[1] >= 0:
+		return NewZipf(arg(0), args[1], arg(2)), nil
+	case name == "scan" && len(args) <= 1:
+		return &Scan{Offset: arg(0)}, nil
+	case name == "loop" && (len(args) == 1 || len(args) == 2) && arg(0) > 0:
+		return &Loop{Blocks: arg(0), Offset: arg(1)}, nil
+	}
+	return nil, fmt.Errorf("unknown pattern or wrong arguments: %q", text)
+}
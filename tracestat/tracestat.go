@@ -0,0 +1,209 @@
+package tracestat
+
+import (
+	"fmt"
+	"io"
+
+	"golang/mrc"
+	"golang/simulator"
+)
+
+// Bucket counts the values between Min and Max inclusive.
+type Bucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// Histogram groups values in power of two buckets: 1, 2-3, 4-7 and so on.
+type Histogram []Bucket
+
+func (h *Histogram) add(value, count int) {
+	k := 0
+	for v := value; v > 1; v >>= 1 {
+		k++
+	}
+	for len(*h) <= k {
+		min := 1 << len(*h)
+		*h = append(*h, Bucket{Min: min, Max: 2*min - 1})
+	}
+	(*h)[k].Count += count
+}
+
+// Distances summarizes how far apart, in accesses, two related accesses to
+// the same block are.
+type Distances struct {
+	Count     int       `json:"count"`
+	Mean      float64   `json:"mean"`
+	Histogram Histogram `json:"histogram"`
+}
+
+func (d *Distances) add(distance int) {
+	d.Mean += (float64(distance) - d.Mean) / float64(d.Count+1)
+	d.Count++
+	d.Histogram.add(distance, 1)
+}
+
+// Report characterizes a trace independently of any cache policy.
+type Report struct {
+	Trace     string  `json:"trace"`
+	Accesses  int     `json:"accesses"`
+	Reads     int     `json:"reads"`
+	Writes    int     `json:"writes"`
+	Trims     int     `json:"trims"`
+	ReadRatio float64 `json:"read_ratio"`
+	Footprint int     `json:"footprint"`
+	// OneHitWonders are blocks accessed only once.
+	OneHitWonders     int     `json:"one_hit_wonders"`
+	OneHitWonderRatio float64 `json:"one_hit_wonder_ratio"`
+	// ReuseDistance is the LRU stack distance of every access that is not
+	// the first one to its block; ColdAccesses counts the others.
+	ReuseDistance Histogram `json:"reuse_distance"`
+	ColdAccesses  int       `json:"cold_accesses"`
+	// WriteAfterWrite is measured from the previous write to the same block,
+	// ReadAfterWrite from the write whose data a read returns.
+	WriteAfterWrite Distances `json:"write_after_write"`
+	ReadAfterWrite  Distances `json:"read_after_write"`
+	// SequentialAccesses access the block right after the previous one;
+	// Sequentiality is their share of all accesses.
+	SequentialAccesses int     `json:"sequential_accesses"`
+	Sequentiality      float64 `json:"sequentiality"`
+	MeanRunLength      float64 `json:"mean_run_length"`
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Collector builds a Report from the records it is given, in trace order.
+type Collector struct {
+	report    Report
+	stack     *mrc.Analyzer
+	counts    map[int]int
+	lastWrite map[int]int
+	prevAddr  int
+	runs      int
+}
+
+func NewCollector(traceName string) *Collector {
+	return &Collector{
+		report:    Report{Trace: traceName},
+		stack:     mrc.NewAnalyzer(),
+		counts:    make(map[int]int),
+		lastWrite: make(map[int]int),
+	}
+}
+
+func (c *Collector) Add(trace simulator.Trace) {
+	switch trace.Op {
+	case simulator.OpTrim:
+		c.report.Trims++
+		delete(c.lastWrite, trace.Addr)
+		c.stack.Access(trace)
+		return
+	case simulator.OpFlush:
+		return
+	}
+
+	now := c.report.Accesses
+	if now > 0 && trace.Addr == c.prevAddr+1 {
+		c.report.SequentialAccesses++
+	} else {
+		c.runs++
+	}
+	c.prevAddr = trace.Addr
+	c.report.Accesses++
+	c.counts[trace.Addr]++
+	c.stack.Access(trace)
+
+	last, written := c.lastWrite[trace.Addr]
+	if trace.Op == simulator.OpWrite {
+		c.report.Writes++
+		if written {
+			c.report.WriteAfterWrite.add(now - last)
+		}
+		c.lastWrite[trace.Addr] = now
+	} else {
+		c.report.Reads++
+		if written {
+			c.report.ReadAfterWrite.add(now - last)
+		}
+	}
+}
+
+// Report returns the characterization of the records added so far.
+func (c *Collector) Report() Report {
+	r := c.report
+	r.Footprint = len(c.counts)
+	r.ReadRatio = ratio(r.Reads, r.Accesses)
+	r.OneHitWonders = 0
+	for _, count := range c.counts {
+		if count == 1 {
+			r.OneHitWonders++
+		}
+	}
+	r.OneHitWonderRatio = ratio(r.OneHitWonders, r.Footprint)
+	r.Sequentiality = ratio(r.SequentialAccesses, r.Accesses)
+	r.ReuseDistance = nil
+	for d, count := range c.stack.Distances() {
+		if count > 0 {
+			r.ReuseDistance.add(d+1, count)
+		}
+	}
+	r.ColdAccesses = c.stack.ColdMisses()
+	r.MeanRunLength = ratio(r.Accesses, c.runs)
+	return r
+}
+
+// WriteText writes r in a human readable form.
+func (r Report) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `trace : %v
+accesses : %v
+reads : %v
+writes : %v
+trims : %v
+read ratio : %.4f
+footprint : %v blocks
+one-hit wonders : %v (%.4f of the footprint)
+sequentiality : %.4f (mean run length %.2f)
+cold accesses : %v
+`, r.Trace, r.Accesses, r.Reads, r.Writes, r.Trims, r.ReadRatio, r.Footprint,
+		r.OneHitWonders, r.OneHitWonderRatio, r.Sequentiality, r.MeanRunLength, r.ColdAccesses)
+	if err != nil {
+		return err
+	}
+
+	if err = writeHistogram(w, "reuse distance", r.ReuseDistance); err != nil {
+		return err
+	}
+	for _, d := range []struct {
+		name string
+		Distances
+	}{
+		{"write after write", r.WriteAfterWrite},
+		{"read after write", r.ReadAfterWrite},
+	} {
+		if _, err = fmt.Fprintf(w, "%v : %v (mean distance %.2f)\n", d.name, d.Count, d.Mean); err != nil {
+			return err
+		}
+		if err = writeHistogram(w, d.name+" distance", d.Histogram); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHistogram(w io.Writer, name string, h Histogram) error {
+	if _, err := fmt.Fprintf(w, "%v histogram :\n", name); err != nil {
+		return err
+	}
+	for _, b := range h {
+		if _, err := fmt.Fprintf(w, "  %v-%v : %v\n", b.Min, b.Max, b.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
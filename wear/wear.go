@@ -0,0 +1,110 @@
+package wear
+
+import (
+	"fmt"
+	"sort"
+
+	"golang/simulator"
+)
+
+type Config struct {
+	// Capacity is the number of pages of the device and PECycles the
+	// number of times each of them can be programmed and erased.
+	Capacity int
+	PECycles int
+	// Top is the number of hottest pages reported.
+	Top int
+}
+
+func (c Config) Validate() error {
+	if c.Capacity < 1 || c.PECycles < 1 || c.Top < 0 {
+		return fmt.Errorf("invalid endurance model: %d pages, %d P/E cycles, top %d", c.Capacity, c.PECycles, c.Top)
+	}
+	return nil
+}
+
+type Page struct {
+	Addr   int `json:"addr"`
+	Writes int `json:"writes"`
+}
+
+// Stats describes how the write-backs of one run spread over the logical
+// pages and how long the device would last under them. Lifetimes are counted
+// in replays of the whole trace and are 0 when nothing was written.
+type Stats struct {
+	Writes       int     `json:"writes"`
+	PagesWritten int     `json:"pages_written"`
+	MeanWrites   float64 `json:"mean_writes_per_page"`
+	P50Writes    int     `json:"p50_writes_per_page"`
+	P90Writes    int     `json:"p90_writes_per_page"`
+	P99Writes    int     `json:"p99_writes_per_page"`
+	MaxWrites    int     `json:"max_writes_per_page"`
+	Hottest      []Page  `json:"hottest"`
+	// WriteAmplification multiplies the write-backs into programs; it is 1
+	// unless an FTL measured it.
+	WriteAmplification float64 `json:"write_amplification"`
+	// Lifetime assumes perfect wear leveling spreads the programs over the
+	// whole capacity; LifetimeUnleveled assumes none, so the hottest page
+	// wears out its block first.
+	Lifetime          float64 `json:"lifetime_replays"`
+	LifetimeUnleveled float64 `json:"lifetime_replays_unleveled"`
+}
+
+// Counter counts the write-backs of every address. It is a
+// simulator.Observer.
+type Counter struct {
+	writes map[int]int
+	total  int
+}
+
+func NewCounter() *Counter {
+	return &Counter{writes: make(map[int]int)}
+}
+
+func (c *Counter) Observe(e simulator.Event) {
+	if e.Kind.IsWriteBack() {
+		c.writes[e.Addr]++
+		c.total++
+	}
+}
+
+func (c *Counter) Stats(config Config, writeAmplification float64) Stats {
+	stats := Stats{
+		Writes:             c.total,
+		PagesWritten:       len(c.writes),
+		WriteAmplification: writeAmplification,
+	}
+	if c.total == 0 {
+		return stats
+	}
+
+	pages := make([]Page, 0, len(c.writes))
+	for addr, writes := range c.writes {
+		pages = append(pages, Page{Addr: addr, Writes: writes})
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].Writes != pages[j].Writes {
+			return pages[i].Writes > pages[j].Writes
+		}
+		return pages[i].Addr < pages[j].Addr
+	})
+
+	// pages is sorted from the hottest, so percentile p sits p% from the end
+	percentile := func(p float64) int {
+		return pages[int(float64(len(pages)-1)*(1-p))].Writes
+	}
+	stats.MeanWrites = float64(c.total) / float64(len(pages))
+	stats.P50Writes = percentile(0.50)
+	stats.P90Writes = percentile(0.90)
+	stats.P99Writes = percentile(0.99)
+	stats.MaxWrites = pages[0].Writes
+	if config.Top < len(pages) {
+		pages = pages[:config.Top]
+	}
+	stats.Hottest = pages
+
+	programs := float64(c.total) * writeAmplification
+	stats.Lifetime = float64(config.Capacity) * float64(config.PECycles) / programs
+	stats.LifetimeUnleveled = float64(config.PECycles) / (float64(stats.MaxWrites) * writeAmplification)
+	return stats
+}